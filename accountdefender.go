@@ -0,0 +1,102 @@
+package recaptcha
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"net/url"
+)
+
+// HashAccountID returns the keyed HMAC-SHA256 of an account ID, to be sent in Event.HashedAccountID instead of
+// the ID itself. The key should be a secret of the application and must not change, otherwise the accounts
+// will not be recognized across assessments
+func HashAccountID(key []byte, accountID string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(accountID))
+	return mac.Sum(nil)
+}
+
+// UserInfo identifies the account of the user, it is used by Account Defender
+type UserInfo struct {
+	// AccountID is a stable identifier of the account, it should not contain personal data
+	AccountID string `json:"accountId,omitempty"`
+}
+
+// AccountDefenderLabel is a label given by Account Defender to an assessed account, read more at
+// https://cloud.google.com/recaptcha-enterprise/docs/reference/rest/v1/projects.assessments#accountdefenderlabel
+type AccountDefenderLabel string
+
+// The labels returned by the API
+const (
+	AccountDefenderLabelUnspecified               AccountDefenderLabel = "ACCOUNT_DEFENDER_LABEL_UNSPECIFIED"
+	AccountDefenderLabelProfileMatch              AccountDefenderLabel = "PROFILE_MATCH"
+	AccountDefenderLabelSuspiciousLoginActivity   AccountDefenderLabel = "SUSPICIOUS_LOGIN_ACTIVITY"
+	AccountDefenderLabelSuspiciousAccountCreation AccountDefenderLabel = "SUSPICIOUS_ACCOUNT_CREATION"
+	AccountDefenderLabelRelatedAccountsNumberHigh AccountDefenderLabel = "RELATED_ACCOUNTS_NUMBER_HIGH"
+)
+
+// HasAccountDefenderLabel reports whether Account Defender gave the label to the account of the assessment
+func (r *EnterpriseResponse) HasAccountDefenderLabel(label AccountDefenderLabel) bool {
+	for _, l := range r.AccountDefenderLabels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+type accountDefenderAssessment struct {
+	Labels []AccountDefenderLabel `json:"labels"`
+}
+
+// RelatedAccountGroupMembership tells that an account belongs to a group of related accounts
+type RelatedAccountGroupMembership struct {
+	// Name is the resource name of the membership
+	// (projects/{project}/relatedaccountgroups/{group}/memberships/{membership})
+	Name            string `json:"name"`
+	HashedAccountID []byte `json:"hashedAccountId"`
+	AccountID       string `json:"accountId"`
+}
+
+// RelatedAccountGroupMembershipsPage is a page of the results of SearchRelatedAccountGroupMemberships
+type RelatedAccountGroupMembershipsPage struct {
+	Memberships []RelatedAccountGroupMembership `json:"relatedAccountGroupMemberships"`
+	// NextPageToken retrieves the next page, it is empty on the last page
+	NextPageToken string `json:"nextPageToken"`
+}
+
+type searchMembershipsRequest struct {
+	HashedAccountID []byte `json:"hashedAccountId"`
+	PageSize        int    `json:"pageSize,omitempty"`
+	PageToken       string `json:"pageToken,omitempty"`
+}
+
+// SearchRelatedAccountGroupMemberships returns a page of the group memberships of the account, which
+// tells which other accounts are related to it. Parameters:
+//  - hashedAccountID The hash of the account ID, see HashAccountID
+//  - pageSize (optional) The maximum number of results, the API decides it when it is 0
+//  - pageToken The NextPageToken of the previous page, or an empty string for the first one
+func (e *Enterprise) SearchRelatedAccountGroupMemberships(ctx context.Context, hashedAccountID []byte, pageSize int, pageToken string) (RelatedAccountGroupMembershipsPage, error) {
+	var page RelatedAccountGroupMembershipsPage
+	request := searchMembershipsRequest{HashedAccountID: hashedAccountID, PageSize: pageSize, PageToken: pageToken}
+	_, err := e.post(ctx, "projects/"+url.PathEscape(e.project)+"/relatedaccountgroupmemberships:search", request, &page)
+	return page, err
+}
+
+// RelatedAccountGroupMemberships returns every group membership of the account, following the pages of
+// SearchRelatedAccountGroupMemberships
+func (e *Enterprise) RelatedAccountGroupMemberships(ctx context.Context, hashedAccountID []byte) ([]RelatedAccountGroupMembership, error) {
+	var memberships []RelatedAccountGroupMembership
+	pageToken := ""
+	for {
+		page, err := e.SearchRelatedAccountGroupMemberships(ctx, hashedAccountID, 0, pageToken)
+		if err != nil {
+			return memberships, err
+		}
+		memberships = append(memberships, page.Memberships...)
+		if page.NextPageToken == "" || page.NextPageToken == pageToken {
+			return memberships, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
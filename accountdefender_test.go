@@ -0,0 +1,100 @@
+package recaptcha_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+func TestHashAccountID(t *testing.T) {
+	// RFC 4231 test case 2
+	hash := recaptcha.HashAccountID([]byte("Jefe"), "what do ya want for nothing?")
+	if hex.EncodeToString(hash) != "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843" {
+		t.Errorf("unexpected hash %x", hash)
+	}
+}
+
+func TestEnterpriseAccountDefender(t *testing.T) {
+	hashedID := recaptcha.HashAccountID([]byte("secret"), "user-42")
+	server := enterpriseServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+		event, _ := body["event"].(map[string]interface{})
+		if event["hashedAccountId"] != base64.StdEncoding.EncodeToString(hashedID) {
+			t.Errorf("unexpected hashedAccountId %v", event["hashedAccountId"])
+		}
+		if userInfo, _ := event["userInfo"].(map[string]interface{}); userInfo["accountId"] != "opaque-42" {
+			t.Errorf("unexpected userInfo %v", event["userInfo"])
+		}
+		jsonReply(`{
+			"name": "projects/my-project/assessments/1",
+			"tokenProperties": {"valid": true},
+			"riskAnalysis": {"score": 0.3},
+			"accountDefenderAssessment": {"labels": ["SUSPICIOUS_LOGIN_ACTIVITY", "RELATED_ACCOUNTS_NUMBER_HIGH"]}
+		}`)(w, r)
+	})
+	defer server.Close()
+	enterprise := newTestEnterprise(server.URL)
+	resp, err := enterprise.Assess(context.Background(), recaptcha.Event{
+		Token:           gResponse,
+		HashedAccountID: hashedID,
+		UserInfo:        &recaptcha.UserInfo{AccountID: "opaque-42"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %v", err)
+	}
+	if len(resp.AccountDefenderLabels) != 2 || !resp.HasAccountDefenderLabel(recaptcha.AccountDefenderLabelSuspiciousLoginActivity) {
+		t.Errorf("unexpected labels %v", resp.AccountDefenderLabels)
+	}
+	if resp.HasAccountDefenderLabel(recaptcha.AccountDefenderLabelProfileMatch) {
+		t.Error("the account should not have the PROFILE_MATCH label")
+	}
+}
+
+func TestEnterpriseRelatedAccountGroupMemberships(t *testing.T) {
+	hashedID := recaptcha.HashAccountID([]byte("secret"), "user-42")
+	pages := map[string]string{
+		"": `{"relatedAccountGroupMemberships": [
+			{"name": "projects/my-project/relatedaccountgroups/g1/memberships/m1", "hashedAccountId": "YWJj"},
+			{"name": "projects/my-project/relatedaccountgroups/g1/memberships/m2", "accountId": "opaque-7"}
+		], "nextPageToken": "page-2"}`,
+		"page-2": `{"relatedAccountGroupMemberships": [
+			{"name": "projects/my-project/relatedaccountgroups/g2/memberships/m3"}
+		]}`,
+	}
+	requests := 0
+	server := enterpriseServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+		requests++
+		if r.URL.Path != "/projects/my-project/relatedaccountgroupmemberships:search" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if body["hashedAccountId"] != base64.StdEncoding.EncodeToString(hashedID) {
+			t.Errorf("unexpected hashedAccountId %v", body["hashedAccountId"])
+		}
+		pageToken, _ := body["pageToken"].(string)
+		jsonReply(pages[pageToken])(w, r)
+	})
+	defer server.Close()
+	enterprise := newTestEnterprise(server.URL)
+
+	page, err := enterprise.SearchRelatedAccountGroupMemberships(context.Background(), hashedID, 2, "")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %v", err)
+	}
+	if len(page.Memberships) != 2 || page.NextPageToken != "page-2" || !bytes.Equal(page.Memberships[0].HashedAccountID, []byte("abc")) ||
+		page.Memberships[1].AccountID != "opaque-7" {
+		t.Errorf("unexpected page %+v", page)
+	}
+
+	requests = 0
+	memberships, err := enterprise.RelatedAccountGroupMemberships(context.Background(), hashedID)
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %v", err)
+	}
+	if len(memberships) != 3 || requests != 2 {
+		t.Errorf("every page should be fetched but got %d memberships in %d requests", len(memberships), requests)
+	}
+}
@@ -0,0 +1,127 @@
+package recaptcha
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// AssessmentName is the resource name of a reCAPTCHA Enterprise assessment (projects/{project}/assessments/{id}).
+// It can be stored along with the events of an application, as text, JSON or in a SQL column, to annotate
+// the assessment later on
+type AssessmentName string
+
+// ParseAssessmentName validates an assessment name
+func ParseAssessmentName(name string) (AssessmentName, error) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 4 || parts[0] != "projects" || parts[1] == "" || parts[2] != "assessments" || parts[3] == "" {
+		return "", fmt.Errorf("invalid assessment name %q", name)
+	}
+	return AssessmentName(name), nil
+}
+
+// Project returns the project of the assessment, or an empty string if the name is invalid
+func (n AssessmentName) Project() string {
+	if _, err := ParseAssessmentName(string(n)); err != nil {
+		return ""
+	}
+	return strings.Split(string(n), "/")[1]
+}
+
+// ID returns the ID of the assessment, or an empty string if the name is invalid
+func (n AssessmentName) ID() string {
+	if _, err := ParseAssessmentName(string(n)); err != nil {
+		return ""
+	}
+	return strings.Split(string(n), "/")[3]
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (n AssessmentName) MarshalText() ([]byte, error) {
+	return []byte(n), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, empty names are accepted and the others are validated
+func (n *AssessmentName) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*n = ""
+		return nil
+	}
+	name, err := ParseAssessmentName(string(text))
+	if err != nil {
+		return err
+	}
+	*n = name
+	return nil
+}
+
+// Value implements driver.Valuer, empty names are stored as NULL
+func (n AssessmentName) Value() (driver.Value, error) {
+	if n == "" {
+		return nil, nil
+	}
+	return string(n), nil
+}
+
+// Scan implements sql.Scanner
+func (n *AssessmentName) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*n = ""
+		return nil
+	case string:
+		return n.UnmarshalText([]byte(value))
+	case []byte:
+		return n.UnmarshalText(value)
+	}
+	return fmt.Errorf("unable to scan %T into an AssessmentName", src)
+}
+
+// Annotation tells whether an assessed interaction turned out to be legitimate or fraudulent
+type Annotation string
+
+const (
+	// AnnotationLegitimate means the interaction was legitimate
+	AnnotationLegitimate Annotation = "LEGITIMATE"
+	// AnnotationFraudulent means the interaction was fraudulent
+	AnnotationFraudulent Annotation = "FRAUDULENT"
+)
+
+// AnnotationReason gives details about an annotation, read more at
+// https://cloud.google.com/recaptcha-enterprise/docs/reference/rest/v1/projects.assessments/annotate#reason
+type AnnotationReason string
+
+// The reasons accepted by the API
+const (
+	AnnotationReasonChargeback          AnnotationReason = "CHARGEBACK"
+	AnnotationReasonChargebackFraud     AnnotationReason = "CHARGEBACK_FRAUD"
+	AnnotationReasonChargebackDispute   AnnotationReason = "CHARGEBACK_DISPUTE"
+	AnnotationReasonRefund              AnnotationReason = "REFUND"
+	AnnotationReasonRefundFraud         AnnotationReason = "REFUND_FRAUD"
+	AnnotationReasonTransactionAccepted AnnotationReason = "TRANSACTION_ACCEPTED"
+	AnnotationReasonTransactionDeclined AnnotationReason = "TRANSACTION_DECLINED"
+	AnnotationReasonPaymentHeuristics   AnnotationReason = "PAYMENT_HEURISTICS"
+	AnnotationReasonInitiatedTwoFactor  AnnotationReason = "INITIATED_TWO_FACTOR"
+	AnnotationReasonPassedTwoFactor     AnnotationReason = "PASSED_TWO_FACTOR"
+	AnnotationReasonFailedTwoFactor     AnnotationReason = "FAILED_TWO_FACTOR"
+	AnnotationReasonCorrectPassword     AnnotationReason = "CORRECT_PASSWORD"
+	AnnotationReasonIncorrectPassword   AnnotationReason = "INCORRECT_PASSWORD"
+	AnnotationReasonSocialSpam          AnnotationReason = "SOCIAL_SPAM"
+)
+
+type annotateRequest struct {
+	Annotation Annotation         `json:"annotation,omitempty"`
+	Reasons    []AnnotationReason `json:"reasons,omitempty"`
+}
+
+// Annotate tells the API whether an assessed interaction turned out to be legitimate or fraudulent,
+// which tunes the risk analysis of the site. The annotation can be empty if only reasons are provided
+func (e *Enterprise) Annotate(ctx context.Context, name AssessmentName, annotation Annotation, reasons ...AnnotationReason) error {
+	if _, err := ParseAssessmentName(string(name)); err != nil {
+		return err
+	}
+	var result struct{}
+	_, err := e.post(ctx, string(name)+":annotate", annotateRequest{Annotation: annotation, Reasons: reasons}, &result)
+	return err
+}
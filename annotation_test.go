@@ -0,0 +1,71 @@
+package recaptcha_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+const assessmentName = "projects/my-project/assessments/b6ac310000000000"
+
+func TestEnterpriseAnnotate(t *testing.T) {
+	server := enterpriseServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+		if r.URL.Path != "/"+assessmentName+":annotate" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if body["annotation"] != "FRAUDULENT" {
+			t.Errorf("the annotation should be FRAUDULENT but it was %v", body["annotation"])
+		}
+		reasons, _ := body["reasons"].([]interface{})
+		if len(reasons) != 2 || reasons[0] != "CHARGEBACK" || reasons[1] != "REFUND_FRAUD" {
+			t.Errorf("unexpected reasons %v", body["reasons"])
+		}
+		jsonReply(`{}`)(w, r)
+	})
+	defer server.Close()
+	enterprise := newTestEnterprise(server.URL)
+	err := enterprise.Annotate(context.Background(), assessmentName, recaptcha.AnnotationFraudulent,
+		recaptcha.AnnotationReasonChargeback, recaptcha.AnnotationReasonRefundFraud)
+	if err != nil {
+		t.Errorf("unexpected error occurred: %v", err)
+	}
+
+	if err := enterprise.Annotate(context.Background(), "assessments/1", recaptcha.AnnotationLegitimate); err == nil {
+		t.Error("an error was expected for an invalid assessment name")
+	}
+}
+
+func TestAssessmentName(t *testing.T) {
+	name, err := recaptcha.ParseAssessmentName(assessmentName)
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %v", err)
+	}
+	if name.Project() != "my-project" || name.ID() != "b6ac310000000000" {
+		t.Errorf("unexpected project %q or ID %q", name.Project(), name.ID())
+	}
+
+	var event struct {
+		Assessment recaptcha.AssessmentName `json:"assessment"`
+	}
+	if err := json.Unmarshal([]byte(`{"assessment": "`+assessmentName+`"}`), &event); err != nil || event.Assessment != name {
+		t.Errorf("the name should be decoded from JSON but got %q, %v", event.Assessment, err)
+	}
+	if err := json.Unmarshal([]byte(`{"assessment": "bogus"}`), &event); err == nil {
+		t.Error("an error was expected decoding an invalid name")
+	}
+
+	var scanned recaptcha.AssessmentName
+	value, _ := name.Value()
+	if err := scanned.Scan(value); err != nil || scanned != name {
+		t.Errorf("the name should survive a round trip through the database but got %q, %v", scanned, err)
+	}
+	if err := scanned.Scan(nil); err != nil || scanned != "" {
+		t.Errorf("NULL should be scanned as an empty name but got %q, %v", scanned, err)
+	}
+	if value, _ := recaptcha.AssessmentName("").Value(); value != nil {
+		t.Errorf("empty names should be stored as NULL but got %v", value)
+	}
+}
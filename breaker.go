@@ -0,0 +1,261 @@
+package recaptcha
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a client circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets every call reach the API
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects every call without reaching the API
+	BreakerOpen
+	// BreakerHalfOpen lets a few probe calls reach the API to find out if it has recovered
+	BreakerHalfOpen
+)
+
+// String returns the name of the state
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerSettings configures the circuit breaker of a client
+type BreakerSettings struct {
+	// FailureThreshold is the number of consecutive failed calls that opens the breaker (5 by default)
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before letting probe calls through (30s by default)
+	OpenTimeout time.Duration
+	// HalfOpenMaxRequests is the number of probe calls allowed while half-open, the breaker closes once
+	// all of them succeed (1 by default)
+	HalfOpenMaxRequests int
+	// OnStateChange (optional) is called every time the breaker changes its state
+	OnStateChange func(from, to BreakerState)
+}
+
+// DegradationMode decides what a client answers when the API is unavailable
+type DegradationMode int
+
+const (
+	// FailClosed reports the failure, calls rejected by an open circuit breaker fail with ErrServiceUnavailable
+	FailClosed DegradationMode = iota
+	// FailOpen answers with a synthesized successful response which has Degraded set to true
+	FailOpen
+)
+
+// WithCircuitBreaker protects the API calls of the client with a circuit breaker, once the API fails
+// repeatedly the calls are answered right away according to the degradation mode instead of waiting for it
+func WithCircuitBreaker(settings BreakerSettings) Option {
+	return func(c *Client) {
+		if settings.FailureThreshold <= 0 {
+			settings.FailureThreshold = 5
+		}
+		if settings.OpenTimeout <= 0 {
+			settings.OpenTimeout = 30 * time.Second
+		}
+		if settings.HalfOpenMaxRequests <= 0 {
+			settings.HalfOpenMaxRequests = 1
+		}
+		c.breaker = &circuitBreaker{settings: settings}
+	}
+}
+
+// WithDegradationMode sets what the client answers when the API is unavailable (FailClosed by default)
+func WithDegradationMode(mode DegradationMode) Option {
+	return func(c *Client) {
+		c.degradation = mode
+	}
+}
+
+// BreakerState returns the current state of the client circuit breaker, BreakerClosed if it has none
+func (c *Client) BreakerState() BreakerState {
+	if c.breaker == nil {
+		return BreakerClosed
+	}
+	c.breaker.mu.Lock()
+	defer c.breaker.mu.Unlock()
+	return c.breaker.state
+}
+
+// IsServiceUnavailable reports whether err means that the API could not give a verdict:
+// ErrServiceUnavailable, connection errors, 5xx and 429 responses, non-JSON replies and undecodable bodies.
+// An AuthError is never an unavailability, whatever made the token source fail
+func IsServiceUnavailable(err error) bool {
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return false
+	}
+	var ctErr *ContentTypeError
+	var decodeErr *DecodeError
+	return errors.Is(err, ErrServiceUnavailable) || errors.As(err, &ctErr) || errors.As(err, &decodeErr) || isTransient(err)
+}
+
+// call performs an API call through the circuit breaker and decodes the JSON response body into result,
+// so truncated or garbage bodies count as failures of the API
+func (c *Client) call(ctx context.Context, newRequest func(context.Context) (*http.Request, error), result interface{}) (int, error) {
+	if c.breaker == nil {
+		return c.exchangeJSON(ctx, newRequest, result)
+	}
+	ticket, allowed := c.breaker.allow(c.now())
+	if !allowed {
+		return 0, ErrServiceUnavailable
+	}
+
+	attempts, err := c.exchangeJSON(ctx, newRequest, result)
+	switch {
+	case err == nil:
+		c.breaker.done(c.now(), ticket, outcomeSuccess)
+	case IsServiceUnavailable(err) && ctx.Err() == nil:
+		c.breaker.done(c.now(), ticket, outcomeFailure)
+	default:
+		// neither the caller canceling the call nor a 4xx response say anything about the API health
+		c.breaker.done(c.now(), ticket, outcomeIgnored)
+	}
+	return attempts, err
+}
+
+// exchangeJSON performs an API call and decodes its response body into result
+func (c *Client) exchangeJSON(ctx context.Context, newRequest func(context.Context) (*http.Request, error), result interface{}) (int, error) {
+	body, attempts, err := c.exchange(ctx, newRequest)
+	if err != nil {
+		return attempts, err
+	}
+	return attempts, unmarshalJSONBody(body, result)
+}
+
+// degrade fills result with a synthesized successful response if the client fails open
+func (c *Client) degrade(err error, result verdict) bool {
+	if c.degradation != FailOpen || !IsServiceUnavailable(err) {
+		return false
+	}
+	response := result.response()
+	response.Success = true
+	response.Degraded = true
+	response.Errors = nil
+	return true
+}
+
+type callOutcome int
+
+const (
+	outcomeSuccess callOutcome = iota
+	outcomeFailure
+	outcomeIgnored
+)
+
+type circuitBreaker struct {
+	mu       sync.Mutex
+	settings BreakerSettings
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	// probes and successes count the calls made while half-open
+	probes    int
+	successes int
+	// generation changes with the state, the calls allowed in an earlier state do not count
+	generation uint64
+}
+
+// breakerTicket identifies an allowed call, it is given back to done once the call finishes
+type breakerTicket struct {
+	generation uint64
+	probe      bool
+}
+
+// allow reports whether a call can go through, every allowed call must be followed by a done call
+func (b *circuitBreaker) allow(now time.Time) (breakerTicket, bool) {
+	b.mu.Lock()
+	from := b.state
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= b.settings.OpenTimeout {
+		b.setState(BreakerHalfOpen, now)
+	}
+
+	allowed := true
+	switch b.state {
+	case BreakerOpen:
+		allowed = false
+	case BreakerHalfOpen:
+		allowed = b.probes < b.settings.HalfOpenMaxRequests
+		if allowed {
+			b.probes++
+		}
+	}
+	ticket := breakerTicket{generation: b.generation, probe: b.state == BreakerHalfOpen}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return ticket, allowed
+}
+
+// done applies the outcome of a call to the breaker, unless the breaker changed its state since the call
+// was allowed: a call made while closed says nothing about the probes of a later half-open state
+func (b *circuitBreaker) done(now time.Time, ticket breakerTicket, outcome callOutcome) {
+	b.mu.Lock()
+	if ticket.generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+	from := b.state
+	switch b.state {
+	case BreakerClosed:
+		if outcome == outcomeSuccess {
+			b.failures = 0
+		} else if outcome == outcomeFailure {
+			b.failures++
+			if b.failures >= b.settings.FailureThreshold {
+				b.setState(BreakerOpen, now)
+			}
+		}
+	case BreakerHalfOpen:
+		if !ticket.probe {
+			break
+		}
+		switch outcome {
+		case outcomeFailure:
+			b.setState(BreakerOpen, now)
+		case outcomeSuccess:
+			b.successes++
+			if b.successes >= b.settings.HalfOpenMaxRequests {
+				b.setState(BreakerClosed, now)
+			}
+		case outcomeIgnored:
+			b.probes--
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// setState must be called with the lock held
+func (b *circuitBreaker) setState(state BreakerState, now time.Time) {
+	b.state = state
+	b.generation++
+	b.failures = 0
+	b.probes = 0
+	b.successes = 0
+	if state == BreakerOpen {
+		b.openedAt = now
+	}
+}
+
+func (b *circuitBreaker) notify(from, to BreakerState) {
+	if from != to && b.settings.OnStateChange != nil {
+		b.settings.OnStateChange(from, to)
+	}
+}
@@ -0,0 +1,242 @@
+package recaptcha_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy, calls int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		jsonReply(`{"success": true}`)(w, r)
+	})
+	defer server.Close()
+
+	var mu sync.Mutex
+	var transitions []string
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := recaptcha.NewClient(
+		recaptcha.WithSecret(apiSecret),
+		recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithClock(func() time.Time { return now }),
+		recaptcha.WithCircuitBreaker(recaptcha.BreakerSettings{
+			FailureThreshold: 2,
+			OpenTimeout:      time.Minute,
+			OnStateChange: func(from, to recaptcha.BreakerState) {
+				mu.Lock()
+				transitions = append(transitions, from.String()+"->"+to.String())
+				mu.Unlock()
+			},
+		}),
+	)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Check(context.Background(), gResponse, ""); !recaptcha.IsServiceUnavailable(err) {
+			t.Errorf("a service unavailable error was expected but got %v", err)
+		}
+	}
+	if state := client.BreakerState(); state != recaptcha.BreakerOpen {
+		t.Fatalf("the breaker should be open but it is %s", state)
+	}
+	_, err := client.Check(context.Background(), gResponse, "")
+	if !errors.Is(err, recaptcha.ErrServiceUnavailable) {
+		t.Errorf("ErrServiceUnavailable was expected but got %v", err)
+	}
+	if calls := atomic.LoadInt32(&calls); calls != 2 {
+		t.Errorf("the API should not be called while the breaker is open but it was called %d times", calls)
+	}
+
+	// once the timeout elapses a probe goes through and closes the breaker
+	now = now.Add(time.Minute)
+	atomic.StoreInt32(&healthy, 1)
+	if resp, err := client.Check(context.Background(), gResponse, ""); err != nil || !resp.Success {
+		t.Errorf("the probe should succeed but got %+v, %v", resp, err)
+	}
+	if state := client.BreakerState(); state != recaptcha.BreakerClosed {
+		t.Errorf("the breaker should be closed but it is %s", state)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(expected) {
+		t.Fatalf("the transitions should be %v but they were %v", expected, transitions)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Errorf("the transitions should be %v but they were %v", expected, transitions)
+		}
+	}
+}
+
+func TestCircuitBreakerHalfOpenFailure(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	defer server.Close()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := recaptcha.NewClient(
+		recaptcha.WithSecret(apiSecret),
+		recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithClock(func() time.Time { return now }),
+		recaptcha.WithCircuitBreaker(recaptcha.BreakerSettings{FailureThreshold: 1, OpenTimeout: time.Second}),
+	)
+	client.Check(context.Background(), gResponse, "")
+	now = now.Add(time.Second)
+	client.Check(context.Background(), gResponse, "")
+	if state := client.BreakerState(); state != recaptcha.BreakerOpen {
+		t.Errorf("a failed probe should open the breaker again but it is %s", state)
+	}
+}
+
+func TestFailOpen(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	defer server.Close()
+	client := recaptcha.NewClient(
+		recaptcha.WithSecret(apiSecret),
+		recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithCircuitBreaker(recaptcha.BreakerSettings{FailureThreshold: 1}),
+		recaptcha.WithDegradationMode(recaptcha.FailOpen),
+	)
+	for i := 0; i < 2; i++ {
+		resp, err := client.CheckV3(context.Background(), gResponse, "")
+		if err != nil || !resp.Success || !resp.Degraded {
+			t.Errorf("a degraded success was expected but got %+v, %v", resp, err)
+		}
+	}
+
+	// verdicts are never degraded
+	server = newTestServer(t, jsonReply(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+	defer server.Close()
+	client = recaptcha.NewClient(
+		recaptcha.WithSecret(apiSecret),
+		recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithDegradationMode(recaptcha.FailOpen),
+	)
+	if resp := client.Verify(gResponse, ""); resp.Success || resp.Degraded {
+		t.Errorf("the verdict should be kept but got %+v", resp)
+	}
+}
+
+func TestCircuitBreakerUndecodableBody(t *testing.T) {
+	var calls int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		jsonReply(`{"success": true, "sco`)(w, r)
+	})
+	defer server.Close()
+	client := recaptcha.NewClient(
+		recaptcha.WithSecret(apiSecret),
+		recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithCircuitBreaker(recaptcha.BreakerSettings{FailureThreshold: 1, OpenTimeout: time.Minute}),
+	)
+	_, err := client.Check(context.Background(), gResponse, "")
+	var decodeErr *recaptcha.DecodeError
+	if !errors.As(err, &decodeErr) || !recaptcha.IsServiceUnavailable(err) {
+		t.Errorf("an unavailable DecodeError was expected but got %v", err)
+	}
+	if state := client.BreakerState(); state != recaptcha.BreakerOpen {
+		t.Fatalf("a truncated body should open the breaker but it is %s", state)
+	}
+
+	client = recaptcha.NewClient(
+		recaptcha.WithSecret(apiSecret),
+		recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithCircuitBreaker(recaptcha.BreakerSettings{FailureThreshold: 1, OpenTimeout: time.Minute}),
+		recaptcha.WithDegradationMode(recaptcha.FailOpen),
+	)
+	atomic.StoreInt32(&calls, 0)
+	for i := 0; i < 3; i++ {
+		resp, err := client.CheckV3(context.Background(), gResponse, "")
+		if err != nil || !resp.Success || !resp.Degraded {
+			t.Errorf("a degraded success was expected but got %+v, %v", resp, err)
+		}
+	}
+	if calls := atomic.LoadInt32(&calls); calls != 1 {
+		t.Errorf("the API should only be called until the breaker opens but it was called %d times", calls)
+	}
+}
+
+func TestCircuitBreakerStaleCall(t *testing.T) {
+	started := map[string]chan struct{}{"slow": make(chan struct{}), "probe": make(chan struct{})}
+	release := map[string]chan struct{}{"slow": make(chan struct{}), "probe": make(chan struct{})}
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		response := r.Form.Get("response")
+		if response == "fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if _, ok := started[response]; ok {
+			close(started[response])
+			<-release[response]
+		}
+		jsonReply(`{"success": true}`)(w, r)
+	})
+	defer server.Close()
+
+	var mu sync.Mutex
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := recaptcha.NewClient(
+		recaptcha.WithSecret(apiSecret),
+		recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithClock(func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		}),
+		recaptcha.WithCircuitBreaker(recaptcha.BreakerSettings{FailureThreshold: 1, OpenTimeout: time.Minute}),
+	)
+	check := func(response string) <-chan error {
+		errs := make(chan error, 1)
+		go func() {
+			_, err := client.Check(context.Background(), response, "")
+			errs <- err
+		}()
+		return errs
+	}
+
+	// a call starts while closed and finishes once the breaker has opened and gone half-open
+	slow := check("slow")
+	<-started["slow"]
+	if err := <-check("fail"); !recaptcha.IsServiceUnavailable(err) {
+		t.Fatalf("a service unavailable error was expected but got %v", err)
+	}
+	mu.Lock()
+	now = now.Add(time.Minute)
+	mu.Unlock()
+	probe := check("probe")
+	<-started["probe"]
+	close(release["slow"])
+	if err := <-slow; err != nil {
+		t.Errorf("unexpected error occurred: %v", err)
+	}
+	if state := client.BreakerState(); state != recaptcha.BreakerHalfOpen {
+		t.Errorf("the stale call should not close the breaker but it is %s", state)
+	}
+	if err := <-check("another"); !errors.Is(err, recaptcha.ErrServiceUnavailable) {
+		t.Errorf("only one probe should go through but got %v", err)
+	}
+
+	close(release["probe"])
+	if err := <-probe; err != nil {
+		t.Errorf("unexpected error occurred: %v", err)
+	}
+	if state := client.BreakerState(); state != recaptcha.BreakerClosed {
+		t.Errorf("the probe should close the breaker but it is %s", state)
+	}
+}
@@ -0,0 +1,309 @@
+package recaptcha
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CassetteEntry is an exchange with the API recorded by RecordingTransport. It is redacted: the secret, the
+// API key, the IP addresses, the account identifiers and the password leak fields are removed and the user
+// response is replaced by its SHA-256 hash, in the request and in the response
+type CassetteEntry struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	URL    string    `json:"url"`
+	// Form holds the form fields of a form request, see redactedFields
+	Form url.Values `json:"form,omitempty"`
+	// RequestBody holds the redacted body of a JSON request, see redactedJSONFields
+	RequestBody string `json:"requestBody,omitempty"`
+	// TokenHash is the hex encoded SHA-256 hash of the user response
+	TokenHash string `json:"tokenHash,omitempty"`
+
+	StatusCode int         `json:"statusCode,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	// Error is the transport error, if the exchange failed
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"durationMs"`
+}
+
+// redactedFields are the form fields which are never recorded
+var redactedFields = []string{"secret", "response", "remoteip", "assertion"}
+
+// redactedJSONFields are the fields of JSON bodies which are never recorded, at any depth. The token fields,
+// which hold the user responses, are replaced by "sha256:" followed by their hash instead
+var redactedJSONFields = map[string]bool{
+	"userIpAddress":                  true,
+	"hashedAccountId":                true,
+	"accountId":                      true,
+	"email":                          true,
+	"phoneNumber":                    true,
+	"lookupHashPrefix":               true,
+	"encryptedUserCredentialsHash":   true,
+	"reencryptedUserCredentialsHash": true,
+	"encryptedLeakMatchPrefixes":     true,
+	"access_token":                   true,
+}
+
+// RecordingTransport is an http.RoundTripper which records the exchanges of a client to a cassette, one JSON
+// encoded CassetteEntry per line. Both the siteverify and the Enterprise exchanges are redacted, see
+// CassetteEntry. Use it with WithHTTPClient:
+//
+//	cassette, err := os.OpenFile("siteverify.jsonl", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+//	transport := recaptcha.NewRecordingTransport(cassette, nil)
+//	client := recaptcha.NewClient(recaptcha.WithHTTPClient(&http.Client{Transport: transport}))
+type RecordingTransport struct {
+	transport http.RoundTripper
+	now       func() time.Time
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewRecordingTransport creates a RecordingTransport writing to out, the requests are sent with transport
+// (http.DefaultTransport if nil)
+func NewRecordingTransport(out io.Writer, transport http.RoundTripper) *RecordingTransport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &RecordingTransport{transport: transport, now: time.Now, out: out}
+}
+
+// RoundTrip sends the request and records the exchange, recording failures are not reported
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry, body, err := newCassetteEntry(req)
+	if err != nil {
+		return nil, err
+	}
+	entry.Time = t.now()
+	if body != nil {
+		req = req.Clone(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	response, err := t.transport.RoundTrip(req)
+	entry.Duration = t.now().Sub(entry.Time).Milliseconds()
+	if err != nil {
+		entry.Error = err.Error()
+		t.write(entry)
+		return nil, err
+	}
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	response.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+	if err != nil {
+		entry.Error = err.Error()
+		t.write(entry)
+		return nil, err
+	}
+	entry.StatusCode = response.StatusCode
+	entry.Header = response.Header
+	entry.Body = string(responseBody)
+	if redacted, _, ok := redactJSON(responseBody); ok {
+		entry.Body = redacted
+	}
+	t.write(entry)
+	return response, nil
+}
+
+func (t *RecordingTransport) write(entry *CassetteEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.out.Write(append(line, '\n'))
+}
+
+// newCassetteEntry returns the redacted entry of a request along with its body, which has been consumed
+func newCassetteEntry(req *http.Request) (*CassetteEntry, []byte, error) {
+	entry := &CassetteEntry{Method: req.Method, URL: redactURL(req.URL)}
+	if req.Body == nil || req.Body == http.NoBody {
+		return entry, nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contentType := req.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		form, err := url.ParseQuery(string(body))
+		if err == nil {
+			if clientResponse := form.Get("response"); clientResponse != "" {
+				entry.TokenHash = tokenHash(clientResponse)
+			}
+			for _, field := range redactedFields {
+				form.Del(field)
+			}
+			entry.Form = form
+		}
+	case strings.HasPrefix(contentType, "application/json"):
+		if redacted, hash, ok := redactJSON(body); ok {
+			entry.RequestBody, entry.TokenHash = redacted, hash
+		}
+	}
+	return entry, body, nil
+}
+
+// redactJSON returns a JSON body without the fields of redactedJSONFields, the body is only re-encoded when
+// a field was redacted. hash is the hash of the first token found and ok is false if the body is not JSON
+func redactJSON(body []byte) (redacted, hash string, ok bool) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return "", "", false
+	}
+	if !redactValue(value, &hash) {
+		return string(body), hash, true
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", "", false
+	}
+	return string(encoded), hash, true
+}
+
+// redactValue redacts a decoded JSON value in place and reports whether anything was redacted
+func redactValue(value interface{}, hash *string) bool {
+	redacted := false
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if token, ok := field.(string); ok && key == "token" && token != "" {
+				if *hash == "" {
+					*hash = tokenHash(token)
+				}
+				value[key] = "sha256:" + tokenHash(token)
+				redacted = true
+			} else if redactedJSONFields[key] {
+				delete(value, key)
+				redacted = true
+			} else if redactValue(field, hash) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for _, element := range value {
+			if redactValue(element, hash) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+// redactURL returns the URL without its key query parameter
+func redactURL(u *url.URL) string {
+	redacted := *u
+	query := redacted.Query()
+	if _, ok := query["key"]; ok {
+		query.Del("key")
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.String()
+}
+
+// ReplayTransport is an http.RoundTripper which answers the requests with the exchanges of a cassette written
+// by RecordingTransport, so a client can reproduce them without network. A request is answered by the first
+// unused entry with the same method, URL and user response, the entries of a failed exchange replay the failure.
+// Requests without a matching entry fail with ErrNoCassetteEntry
+type ReplayTransport struct {
+	mu      sync.Mutex
+	entries []CassetteEntry
+	used    []bool
+}
+
+// NewReplayTransport creates a ReplayTransport with the cassette read from r
+func NewReplayTransport(r io.Reader) (*ReplayTransport, error) {
+	t := &ReplayTransport{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry CassetteEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid cassette entry on line %d: %w", line, err)
+		}
+		t.entries = append(t.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	t.used = make([]bool, len(t.entries))
+	return t, nil
+}
+
+// RoundTrip answers the request with the matching entry of the cassette
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	request, _, err := newCassetteEntry(req)
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := t.take(request)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoCassetteEntry, request.Method, request.URL)
+	}
+	if entry.Error != "" {
+		return nil, errors.New(entry.Error)
+	}
+
+	header := entry.Header
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.StatusCode, http.StatusText(entry.StatusCode)),
+		StatusCode:    entry.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header.Clone(),
+		Body:          ioutil.NopCloser(strings.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       req,
+	}, nil
+}
+
+// take returns the first unused entry matching the request and marks it as used
+func (t *ReplayTransport) take(request *CassetteEntry) (CassetteEntry, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, entry := range t.entries {
+		if !t.used[i] && entry.Method == request.Method && entry.URL == request.URL && entry.TokenHash == request.TokenHash {
+			t.used[i] = true
+			return entry, true
+		}
+	}
+	return CassetteEntry{}, false
+}
+
+// Remaining returns how many entries of the cassette were not replayed yet
+func (t *ReplayTransport) Remaining() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	remaining := 0
+	for _, used := range t.used {
+		if !used {
+			remaining++
+		}
+	}
+	return remaining
+}
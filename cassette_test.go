@@ -0,0 +1,132 @@
+package recaptcha_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.Form.Get("response") {
+		case gResponse:
+			jsonReply(`{"success": true, "score": 0.7, "action": "login", "hostname": "example.com"}`)(w, r)
+		case "outage":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			jsonReply(`{"success": false, "error-codes": ["invalid-input-response"]}`)(w, r)
+		}
+	})
+	defer server.Close()
+
+	var cassette bytes.Buffer
+	recorder := recaptcha.NewRecordingTransport(&cassette, nil)
+	client := recaptcha.NewClient(recaptcha.WithSecret(apiSecret), recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithHTTPClient(&http.Client{Transport: recorder}))
+	recorded, err := client.CheckV3(context.Background(), gResponse, clientIP)
+	if err != nil || !recorded.Success {
+		t.Fatalf("unexpected response %+v, %v", recorded, err)
+	}
+	client.Check(context.Background(), "forged", "")
+	_, recordedErr := client.Check(context.Background(), "outage", "")
+
+	lines := strings.Split(strings.TrimSpace(cassette.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("3 exchanges should be recorded but the cassette is:\n%s", cassette.String())
+	}
+	if strings.Contains(cassette.String(), apiSecret) || strings.Contains(cassette.String(), gResponse) {
+		t.Errorf("the secret and the user response should be redacted:\n%s", cassette.String())
+	}
+	var entry recaptcha.CassetteEntry
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("invalid cassette entry: %v", err)
+	}
+	if entry.TokenHash != "e9c0f8b575cbfcb42ab3b78ecc87efa3b011d9a5d10b09fa4e96f240bf6a82f5" || entry.Form.Get("remoteip") != "" ||
+		entry.StatusCode != http.StatusOK || !strings.Contains(entry.Body, `"score": 0.7`) {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+
+	replay, err := recaptcha.NewReplayTransport(strings.NewReader(cassette.String()))
+	if err != nil {
+		t.Fatalf("unable to read the cassette: %v", err)
+	}
+	server.Close()
+	client = recaptcha.NewClient(recaptcha.WithSecret("another-secret"), recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithHTTPClient(&http.Client{Transport: replay}))
+
+	// the exchanges are matched by user response, whatever their order
+	_, err = client.Check(context.Background(), "outage", "")
+	var statusErr *recaptcha.HTTPStatusError
+	if !errors.As(err, &statusErr) || err.Error() != recordedErr.Error() {
+		t.Errorf("the outage should be replayed but got %v", err)
+	}
+	replayed, err := client.CheckV3(context.Background(), gResponse, clientIP)
+	if err != nil || replayed.Success != recorded.Success || replayed.Score != recorded.Score || replayed.Hostname != recorded.Hostname {
+		t.Errorf("the response should be replayed but got %+v, %v", replayed, err)
+	}
+	if replay.Remaining() != 1 {
+		t.Errorf("one entry should remain but %d do", replay.Remaining())
+	}
+
+	if _, err := client.Check(context.Background(), gResponse, clientIP); !errors.Is(err, recaptcha.ErrNoCassetteEntry) {
+		t.Errorf("every entry should only be replayed once but got %v", err)
+	}
+}
+
+func TestReplayTransportInvalidCassette(t *testing.T) {
+	if _, err := recaptcha.NewReplayTransport(strings.NewReader("{\"method\": \"POST\"}\nnot json\n")); err == nil {
+		t.Error("an invalid cassette should be rejected")
+	}
+}
+
+func TestRecordAndReplayEnterprise(t *testing.T) {
+	server := enterpriseServer(t, assessmentReply(t, `{
+		"name": "projects/my-project/assessments/b6ac310000000000",
+		"event": {"token": "ABCDEF", "siteKey": "6LcSiteKey", "userIpAddress": "203.0.113.7", "hashedAccountId": "c2VjcmV0"},
+		"riskAnalysis": {"score": 0.9},
+		"tokenProperties": {"valid": true, "hostname": "example.com", "action": "login"}
+	}`))
+	defer server.Close()
+
+	var cassette bytes.Buffer
+	recorder := recaptcha.NewRecordingTransport(&cassette, nil)
+	enterprise := newTestEnterprise(server.URL, recaptcha.WithHTTPClient(&http.Client{Transport: recorder}))
+	event := recaptcha.Event{Token: gResponse, UserIPAddress: clientIP, HashedAccountID: []byte("secret")}
+	recorded, err := enterprise.Assess(context.Background(), event)
+	if err != nil || !recorded.Success {
+		t.Fatalf("unexpected response %+v, %v", recorded, err)
+	}
+
+	for _, secret := range []string{gResponse, "userIpAddress", "203.0.113.7", "hashedAccountId", enterpriseAPIKey} {
+		if strings.Contains(cassette.String(), secret) {
+			t.Errorf("%s should be redacted from the cassette:\n%s", secret, cassette.String())
+		}
+	}
+	var entry recaptcha.CassetteEntry
+	if err := json.Unmarshal(cassette.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid cassette entry: %v", err)
+	}
+	hash := "e9c0f8b575cbfcb42ab3b78ecc87efa3b011d9a5d10b09fa4e96f240bf6a82f5"
+	if entry.TokenHash != hash || !strings.Contains(entry.RequestBody, `"token":"sha256:`+hash+`"`) ||
+		!strings.Contains(entry.Body, `"token":"sha256:`+hash+`"`) || !strings.Contains(entry.Body, `"score":0.9`) {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+
+	replay, err := recaptcha.NewReplayTransport(&cassette)
+	if err != nil {
+		t.Fatalf("unable to read the cassette: %v", err)
+	}
+	server.Close()
+	enterprise = newTestEnterprise(server.URL, recaptcha.WithHTTPClient(&http.Client{Transport: replay}))
+	replayed, err := enterprise.Assess(context.Background(), event)
+	if err != nil || replayed.Success != recorded.Success || replayed.Score != recorded.Score || replayed.Name != recorded.Name {
+		t.Errorf("the assessment should be replayed but got %+v, %v", replayed, err)
+	}
+}
@@ -0,0 +1,220 @@
+package recaptcha
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultVerifyURL is the Google's siteverify endpoint used when no other URL is provided
+const DefaultVerifyURL = "https://www.google.com/recaptcha/api/siteverify"
+
+// Client verifies user responses against a siteverify endpoint.
+// Every Client carries its own secret, endpoint and HTTP client, so several clients with different
+// configurations can be used at the same time. A Client is thread-safe once created
+type Client struct {
+	secret     string
+	verifyURL  string
+	httpClient *http.Client
+	userAgent  string
+	formField  string
+	// params holds provider specific form parameters sent along with every verification
+	params url.Values
+
+	allowedHostnames    []string
+	allowedPackageNames []string
+	v3Policy            *V3Policy
+
+	maxChallengeAge time.Duration
+	clockSkew       time.Duration
+	now             func() time.Time
+
+	replayStore ReplayStore
+	replayTTL   time.Duration
+	idempotency *idempotencyCache
+
+	retry       *RetryPolicy
+	breaker     *circuitBreaker
+	degradation DegradationMode
+
+	trustedProxies []*net.IPNet
+}
+
+// Option configures a Client, see NewClient
+type Option func(*Client)
+
+// WithSecret sets the Recaptcha API secret key used by the client
+func WithSecret(secret string) Option {
+	return func(c *Client) {
+		c.secret = secret
+	}
+}
+
+// WithVerifyURL overrides the siteverify endpoint (DefaultVerifyURL by default)
+func WithVerifyURL(verifyURL string) Option {
+	return func(c *Client) {
+		c.verifyURL = verifyURL
+	}
+}
+
+// WithHTTPClient sets the http.Client used to reach the siteverify endpoint.
+// If it is not provided the package-level HTTPClient is used
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every verification request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithAllowedHostnames makes the client reject responses solved on a site other than the given ones.
+// A pattern starting with "*." matches any subdomain, e.g. "*.example.com" matches "www.example.com"
+// but not "example.com". Mismatches are reported with ErrHostnameMismatch
+func WithAllowedHostnames(hostnames ...string) Option {
+	return func(c *Client) {
+		c.allowedHostnames = append(c.allowedHostnames, hostnames...)
+	}
+}
+
+// WithAllowedPackageNames makes the client reject responses solved in an Android app other than the given ones.
+// Mismatches are reported with ErrPackageNameMismatch
+func WithAllowedPackageNames(packageNames ...string) Option {
+	return func(c *Client) {
+		c.allowedPackageNames = append(c.allowedPackageNames, packageNames...)
+	}
+}
+
+// WithV3Policy makes the client apply the policy to every Recaptcha v3 response, see ResponseV3.ApplyPolicy
+func WithV3Policy(policy V3Policy) Option {
+	return func(c *Client) {
+		c.v3Policy = &policy
+	}
+}
+
+// WithMaxChallengeAge makes the client reject responses whose ChallengeTimeStamp is older than maxAge,
+// or newer than the current time plus the clock skew tolerance. Rejections are reported with ErrChallengeExpired
+func WithMaxChallengeAge(maxAge time.Duration) Option {
+	return func(c *Client) {
+		c.maxChallengeAge = maxAge
+	}
+}
+
+// WithClockSkew sets how far in the future a ChallengeTimeStamp can be, it defaults to 30 seconds.
+// It only has effect along with WithMaxChallengeAge
+func WithClockSkew(skew time.Duration) Option {
+	return func(c *Client) {
+		c.clockSkew = skew
+	}
+}
+
+// WithClock replaces time.Now as the source of the current time, which is mostly useful for tests
+func WithClock(now func() time.Time) Option {
+	return func(c *Client) {
+		c.now = now
+	}
+}
+
+// WithSiteKey sets the site key sent along with every verification in the sitekey parameter,
+// hCaptcha uses it to check that the user response was issued for that site
+func WithSiteKey(siteKey string) Option {
+	return func(c *Client) {
+		c.params.Set("sitekey", siteKey)
+	}
+}
+
+// NewClient creates a new Client configured with the given options
+func NewClient(opts ...Option) *Client {
+	return newClient(DefaultVerifyURL, DefaultFormField, opts)
+}
+
+// newClient creates a Client for a provider
+func newClient(verifyURL, formField string, opts []Option) *Client {
+	c := &Client{
+		verifyURL: verifyURL,
+		formField: formField,
+		params:    url.Values{},
+		clockSkew: defaultClockSkew,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// FormField returns the form field where the reCAPTCHA widget stores the user response
+func (c *Client) FormField() string {
+	return c.formField
+}
+
+// defaultClient backs the package-level functions
+var defaultClient = NewClient()
+
+// Verify verifies if an user's Recaptcha v2/Invisible response is valid (same as VerifyWithContext with context.Background())
+// Parameters:
+//  - clientResponse The user response token provided by the reCAPTCHA client-side integration of your app
+//  - remoteIP (optional) the user's IP, if provided Recaptcha will check if the user resolved the captcha with same IP
+func (c *Client) Verify(clientResponse, remoteIP string) Response {
+	return c.VerifyWithContext(context.Background(), clientResponse, remoteIP)
+}
+
+// VerifyWithContext verifies if an user's Recaptcha v2/Invisible response is valid
+// Parameters:
+//  - ctx Provides context for cancelation
+//  - clientResponse The user response token provided by the reCAPTCHA client-side integration of your app
+//  - remoteIP (optional) the user's IP, if provided Recaptcha will check if the user resolved the captcha with same IP
+func (c *Client) VerifyWithContext(ctx context.Context, clientResponse, remoteIP string) (response Response) {
+	err := c.verify(ctx, c.secret, clientResponse, remoteIP, &response)
+	if err != nil {
+		response.Errors = []error{err}
+	}
+	return response
+}
+
+// VerifyV3 verifies if an user's Recaptcha v3 response is valid (same as VerifyV3WithContext with context.Background())
+// Parameters:
+//  - clientResponse The user response token provided by the reCAPTCHA client-side integration of your app
+//  - remoteIP (optional) The user's IP address, if provided Recaptcha will check if the user resolved the captcha with same IP
+func (c *Client) VerifyV3(clientResponse, remoteIP string) ResponseV3 {
+	return c.VerifyV3WithContext(context.Background(), clientResponse, remoteIP)
+}
+
+// VerifyV3WithContext verifies if an user's Recaptcha v3 response is valid
+// Parameters:
+//  - ctx Provides context for cancelation
+//  - clientResponse The user response token provided by the reCAPTCHA client-side integration of your app
+//  - remoteIP (optional) The user's IP address, if provided Recaptcha will check if the user resolved the captcha with same IP
+func (c *Client) VerifyV3WithContext(ctx context.Context, clientResponse, remoteIP string) (response ResponseV3) {
+	err := c.verify(ctx, c.secret, clientResponse, remoteIP, &response)
+	if err != nil {
+		response.Errors = []error{err}
+	}
+	return response
+}
+
+// Check verifies an user's Recaptcha v2/Invisible response, transport and protocol failures are reported
+// through err while response.Errors only holds the error codes returned by the API. See the package-level Check
+func (c *Client) Check(ctx context.Context, clientResponse, remoteIP string) (response Response, err error) {
+	err = c.verify(ctx, c.secret, clientResponse, remoteIP, &response)
+	return response, err
+}
+
+// CheckV3 is the Recaptcha v3 counterpart of Check
+func (c *Client) CheckV3(ctx context.Context, clientResponse, remoteIP string) (response ResponseV3, err error) {
+	err = c.verify(ctx, c.secret, clientResponse, remoteIP, &response)
+	return response, err
+}
+
+func (c *Client) client() *http.Client {
+	if c.httpClient != nil {
+		return c.httpClient
+	}
+	return HTTPClient
+}
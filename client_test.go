@@ -0,0 +1,153 @@
+package recaptcha_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+// newTestServer starts a siteverify stand-in served by handler, it must be closed by the caller
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(handler)
+}
+
+func jsonReply(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", jsonCT)
+		w.Write([]byte(body))
+	}
+}
+
+func TestClientVerify(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("error parsing the form: %s", err.Error())
+		}
+		if secret := r.Form.Get("secret"); secret != apiSecret {
+			t.Errorf("the secret should be %s, but %s was found", apiSecret, secret)
+		}
+		if response := r.Form.Get("response"); response != gResponse {
+			t.Errorf("the response should be %s, but %s was found", gResponse, response)
+		}
+		if remoteIP := r.Form.Get("remoteip"); remoteIP != clientIP {
+			t.Errorf("the remoteIP should be %s, but %s was found", clientIP, remoteIP)
+		}
+		if ua := r.Header.Get("User-Agent"); ua != "test-agent" {
+			t.Errorf("the User-Agent should be test-agent, but %s was found", ua)
+		}
+		jsonReply(`{"success": true, "score": 0.7, "action": "login"}`)(w, r)
+	})
+	defer server.Close()
+	client := recaptcha.NewClient(
+		recaptcha.WithSecret(apiSecret),
+		recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithHTTPClient(&http.Client{Timeout: time.Second}),
+		recaptcha.WithUserAgent("test-agent"),
+	)
+
+	t.Run("V2", func(t *testing.T) {
+		resp := client.Verify(gResponse, clientIP)
+		if len(resp.Errors) != 0 {
+			t.Errorf("the errors array should be empty but it contains: %+v", resp.Errors)
+		}
+		if !resp.Success {
+			t.Error("response.Success should be true but it was not")
+		}
+	})
+	t.Run("V3", func(t *testing.T) {
+		resp := client.VerifyV3(gResponse, clientIP)
+		if len(resp.Errors) != 0 {
+			t.Errorf("the errors array should be empty but it contains: %+v", resp.Errors)
+		}
+		if resp.Score != 0.7 || resp.Action != "login" {
+			t.Errorf("unexpected score/action: %f %s", resp.Score, resp.Action)
+		}
+	})
+}
+
+func TestClientMissingSecret(t *testing.T) {
+	client := recaptcha.NewClient(recaptcha.WithVerifyURL("http://127.0.0.1:0"))
+	resp := client.Verify(gResponse, "")
+	errInvalidInputSecretHelper(t, resp)
+}
+
+func TestClientCheckVerdict(t *testing.T) {
+	server := newTestServer(t, jsonReply(`{"success": false, "error-codes": ["timeout-or-duplicate"]}`))
+	defer server.Close()
+	client := recaptcha.NewClient(recaptcha.WithSecret(apiSecret), recaptcha.WithVerifyURL(server.URL))
+	resp, err := client.Check(context.Background(), gResponse, "")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %v", err)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0] != recaptcha.ErrTimeoutOrDuplicate {
+		t.Errorf("the errors array should contain ErrTimeoutOrDuplicate but it contains: %+v", resp.Errors)
+	}
+}
+
+func TestClientCheckErrors(t *testing.T) {
+	t.Run("status", func(t *testing.T) {
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(strings.Repeat("x", 4096)))
+		})
+		defer server.Close()
+		client := recaptcha.NewClient(recaptcha.WithSecret(apiSecret), recaptcha.WithVerifyURL(server.URL))
+		resp, err := client.Check(context.Background(), gResponse, "")
+		var statusErr *recaptcha.HTTPStatusError
+		if !errors.As(err, &statusErr) {
+			t.Fatalf("the error should be an HTTPStatusError but it was %v", err)
+		}
+		if statusErr.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("the status code should be 503 but it was %d", statusErr.StatusCode)
+		}
+		if len(statusErr.Body) != 512 {
+			t.Errorf("the body should be truncated to 512 bytes but it has %d", len(statusErr.Body))
+		}
+		if len(resp.Errors) != 0 {
+			t.Errorf("the errors array should be empty but it contains: %+v", resp.Errors)
+		}
+	})
+	t.Run("content type", func(t *testing.T) {
+		server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		})
+		defer server.Close()
+		client := recaptcha.NewClient(recaptcha.WithSecret(apiSecret), recaptcha.WithVerifyURL(server.URL))
+		_, err := client.Check(context.Background(), gResponse, "")
+		var ctErr *recaptcha.ContentTypeError
+		if !errors.As(err, &ctErr) {
+			t.Fatalf("the error should be a ContentTypeError but it was %v", err)
+		}
+		if ctErr.Body != "<html></html>" {
+			t.Errorf("unexpected body %q", ctErr.Body)
+		}
+	})
+	t.Run("decode", func(t *testing.T) {
+		server := newTestServer(t, jsonReply(`{"success": tr`))
+		defer server.Close()
+		client := recaptcha.NewClient(recaptcha.WithSecret(apiSecret), recaptcha.WithVerifyURL(server.URL))
+		_, err := client.CheckV3(context.Background(), gResponse, "")
+		var decodeErr *recaptcha.DecodeError
+		if !errors.As(err, &decodeErr) {
+			t.Fatalf("the error should be a DecodeError but it was %v", err)
+		}
+	})
+	t.Run("transport", func(t *testing.T) {
+		server := httptest.NewServer(jsonReply(`{"success": true}`))
+		server.Close()
+		client := recaptcha.NewClient(recaptcha.WithSecret(apiSecret), recaptcha.WithVerifyURL(server.URL))
+		_, err := client.Check(context.Background(), gResponse, "")
+		var transportErr *recaptcha.TransportError
+		if !errors.As(err, &transportErr) {
+			t.Fatalf("the error should be a TransportError but it was %v", err)
+		}
+	})
+}
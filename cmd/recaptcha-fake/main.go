@@ -0,0 +1,176 @@
+// Command recaptcha-fake serves a fake reCAPTCHA, both the widget and the siteverify endpoint, to run end-to-end
+// tests offline. Pages load the widget from the fake server instead of Google:
+//
+//	<script src="http://127.0.0.1:8080/recaptcha/api.js" async defer></script>
+//
+// and the backend verifies the tokens against it:
+//
+//	recaptcha.NewClient(recaptcha.WithSecret("recaptchatest-secret"),
+//		recaptcha.WithVerifyURL("http://127.0.0.1:8080/recaptcha/api/siteverify"))
+//
+// The widget solves itself and gets deterministic tokens (FAKE.{site key}.{action}.{sequence}). Its grecaptcha
+// object supports render, execute, ready, getResponse and reset, for Recaptcha v2, invisible and v3 (also
+// under grecaptcha.enterprise). What siteverify answers for the tokens is set by a scenario file, written in JSON
+// only (YAML files have to be converted first, this module has no dependencies):
+//
+//	{
+//	  "secret": "recaptchatest-secret",
+//	  "solveDelay": "500ms",
+//	  "default": {"success": true, "score": 0.9},
+//	  "actions": {
+//	    "login": {"score": 0.2},
+//	    "checkout": {"success": false, "errorCodes": ["timeout-or-duplicate"], "latency": "2s"}
+//	  },
+//	  "siteKeys": {"broken-key": {"success": false, "errorCodes": ["invalid-input-response"]}}
+//	}
+//
+// The outcome of a token is the default one overridden by the outcome of its action, then by the outcome of
+// its site key. Tokens can only be verified once.
+//
+// Siteverify can also misbehave at random, to check how the backend copes with a flaky network. The faults are
+// server_error, rate_limit, html_page, truncated_json, slow_drip and connection_reset:
+//
+//	"faults": {
+//	  "probabilities": {"server_error": 0.1, "rate_limit": 0.05, "connection_reset": 0.02},
+//	  "statusCode": 502, "retryAfter": "2s", "dripInterval": "100ms", "seed": 42
+//	}
+//
+// GET /recaptcha-fake/counters returns how many requests siteverify received, how many were answered normally
+// and how many got each fault, POST /recaptcha-fake/counters/reset zeroes them. Usage:
+//
+//	recaptcha-fake [-addr 127.0.0.1:8080] [-scenario scenario.json]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/claudio4/go-recaptcha/recaptchatest"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8080", "address to listen on")
+	scenarioPath := flag.String("scenario", "", "scenario file, JSON only (optional)")
+	flag.Parse()
+
+	s := defaultScenario()
+	if *scenarioPath != "" {
+		var err error
+		if s, err = loadScenario(*scenarioPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	log.Printf("serving a fake reCAPTCHA on http://%s (secret %q)", *addr, s.Secret)
+	log.Fatal(http.ListenAndServe(*addr, newFakeServer(s)))
+}
+
+// fakeServer serves the widget and the siteverify endpoint
+type fakeServer struct {
+	scenario   scenario
+	siteverify *recaptchatest.Siteverify
+	mux        *http.ServeMux
+
+	mu       sync.Mutex
+	sequence map[string]int
+}
+
+func newFakeServer(s scenario) *fakeServer {
+	server := &fakeServer{
+		scenario:   s,
+		siteverify: recaptchatest.NewSiteverify(s.Secret),
+		mux:        http.NewServeMux(),
+		sequence:   map[string]int{},
+	}
+	server.mux.Handle("/recaptcha/api/siteverify", server.siteverify)
+	server.mux.HandleFunc("/recaptcha/api.js", server.serveAPI)
+	server.mux.HandleFunc("/recaptcha/enterprise.js", server.serveAPI)
+	server.mux.HandleFunc("/recaptcha/api2/anchor", server.serveAnchor)
+	server.mux.HandleFunc("/recaptcha/api2/token", server.serveToken)
+	server.mux.HandleFunc("/recaptcha-fake/counters", server.serveCounters)
+	server.mux.HandleFunc("/recaptcha-fake/counters/reset", server.resetCounters)
+	if s.Faults != nil {
+		server.siteverify.SetFaults(s.Faults.profile())
+	}
+	return server
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// issue creates a token and registers its verdict
+func (s *fakeServer) issue(siteKey, action, hostname string) string {
+	s.mu.Lock()
+	key := siteKey + "." + action
+	s.sequence[key]++
+	token := fmt.Sprintf("FAKE.%s.%s.%d", url.QueryEscape(siteKey), url.QueryEscape(action), s.sequence[key])
+	s.mu.Unlock()
+
+	s.siteverify.Register(token, s.scenario.verdict(siteKey, action, hostname))
+	return token
+}
+
+// origin returns the origin of the fake server as seen by the browser
+func origin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func (s *fakeServer) serveAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := apiTemplate.Execute(w, struct{ Origin string }{origin(r)}); err != nil {
+		log.Printf("unable to render api.js: %v", err)
+	}
+}
+
+func (s *fakeServer) serveAnchor(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	id, _ := strconv.Atoi(query.Get("id"))
+	token := s.issue(query.Get("k"), query.Get("action"), query.Get("host"))
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	data := struct {
+		Token   string
+		ID      int
+		DelayMs int64
+	}{token, id, int64(s.scenario.SolveDelay) / 1e6}
+	if err := anchorTemplate.Execute(w, data); err != nil {
+		log.Printf("unable to render the anchor: %v", err)
+	}
+}
+
+func (s *fakeServer) serveToken(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	token := s.issue(query.Get("k"), query.Get("action"), query.Get("host"))
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprintf(w, "{\"token\": %q}\n", token)
+}
+
+func (s *fakeServer) serveCounters(w http.ResponseWriter, r *http.Request) {
+	counters := s.siteverify.Counters()
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(counters)
+}
+
+func (s *fakeServer) resetCounters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	s.siteverify.ResetCounters()
+	w.WriteHeader(http.StatusNoContent)
+}
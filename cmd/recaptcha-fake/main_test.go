@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+const testScenario = `{
+	"secret": "e2e-secret",
+	"solveDelay": 250,
+	"default": {"score": 0.8},
+	"actions": {
+		"login": {"score": 0.2},
+		"checkout": {"success": false, "errorCodes": ["timeout-or-duplicate"], "latency": "10ms"}
+	},
+	"siteKeys": {"broken-key": {"success": false, "errorCodes": ["invalid-input-response"]}}
+}`
+
+// startFakeServer starts the fake server with testScenario, the server must be closed by the caller
+func startFakeServer(t *testing.T) (*httptest.Server, *recaptcha.Client) {
+	t.Helper()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scenario.json")
+	if err := ioutil.WriteFile(path, []byte(testScenario), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := loadScenario(path)
+	if err != nil {
+		t.Fatalf("unable to load the scenario: %v", err)
+	}
+	if s.SolveDelay != duration(250*time.Millisecond) || *s.Default.Score != 0.8 || !*s.Default.Success {
+		t.Errorf("unexpected scenario %+v", s)
+	}
+
+	server := httptest.NewServer(newFakeServer(s))
+	client := recaptcha.NewClient(recaptcha.WithSecret("e2e-secret"), recaptcha.WithVerifyURL(server.URL+"/recaptcha/api/siteverify"))
+	return server, client
+}
+
+// tempDir creates a temporary directory, it must be removed by the caller
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "recaptcha-fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func fetchToken(t *testing.T, server *httptest.Server, siteKey, action string) string {
+	t.Helper()
+	resp, err := http.Get(server.URL + "/recaptcha/api2/token?k=" + siteKey + "&action=" + action + "&host=example.com")
+	if err != nil {
+		t.Fatalf("unable to get a token: %v", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	return body.Token
+}
+
+func TestFakeServerScenario(t *testing.T) {
+	server, client := startFakeServer(t)
+	defer server.Close()
+
+	token := fetchToken(t, server, "site-key", "submit")
+	if token != "FAKE.site-key.submit.1" {
+		t.Errorf("the token should be deterministic but it was %s", token)
+	}
+	resp, err := client.CheckV3(context.Background(), token, "")
+	if err != nil || !resp.Success || resp.Score != 0.8 || resp.Action != "submit" || resp.Hostname != "example.com" {
+		t.Errorf("unexpected response %+v, %v", resp, err)
+	}
+	if resp, _ := client.CheckV3(context.Background(), token, ""); resp.Success {
+		t.Error("a token should only be verified once")
+	}
+
+	resp, _ = client.CheckV3(context.Background(), fetchToken(t, server, "site-key", "login"), "")
+	if !resp.Success || resp.Score != 0.2 {
+		t.Errorf("the login action should have a 0.2 score but got %+v", resp)
+	}
+	resp, _ = client.CheckV3(context.Background(), fetchToken(t, server, "site-key", "checkout"), "")
+	if resp.Success || len(resp.Errors) != 1 || resp.Errors[0] != recaptcha.ErrTimeoutOrDuplicate {
+		t.Errorf("the checkout action should fail but got %+v", resp)
+	}
+	resp, _ = client.CheckV3(context.Background(), fetchToken(t, server, "broken-key", "login"), "")
+	if resp.Success || len(resp.Errors) != 1 || resp.Errors[0] != recaptcha.ErrInvalidInputResponse {
+		t.Errorf("the broken-key site key should fail but got %+v", resp)
+	}
+	if resp, _ := client.Check(context.Background(), "made-up", ""); resp.Success {
+		t.Error("unknown tokens should be rejected")
+	}
+}
+
+func TestFakeServerWidget(t *testing.T) {
+	server, client := startFakeServer(t)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/recaptcha/api.js?render=explicit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	script, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(resp.Header.Get("Content-Type"), "javascript") || !strings.Contains(string(script), `var origin = "`+server.URL+`"`) {
+		t.Errorf("unexpected api.js (%s):\n%s", resp.Header.Get("Content-Type"), script)
+	}
+
+	resp, err = http.Get(server.URL + "/recaptcha/api2/anchor?k=site-key&host=example.com&id=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	anchor, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	match := regexp.MustCompile(`id:\s*1\s*, token: "([^"]+)"}, "\*"\);\n},\s*250\s*\);`).FindSubmatch(anchor)
+	if match == nil {
+		t.Fatalf("the anchor should post its token after the solve delay:\n%s", anchor)
+	}
+	v2, err := client.Check(context.Background(), string(match[1]), "")
+	if err != nil || !v2.Success {
+		t.Errorf("the widget token should be valid but got %+v, %v", v2, err)
+	}
+}
+
+func TestLoadScenarioErrors(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{"invalid.json": `{"default": `, "duration.json": `{"solveDelay": "soon"}`} {
+		path := filepath.Join(dir, name)
+		ioutil.WriteFile(path, []byte(content), 0600)
+		if _, err := loadScenario(path); err == nil {
+			t.Errorf("%s should not be loaded", name)
+		}
+	}
+	path := filepath.Join(dir, "scenario.yaml")
+	ioutil.WriteFile(path, []byte("secret: e2e-secret\n"), 0600)
+	if _, err := loadScenario(path); err == nil || !strings.Contains(err.Error(), "only JSON") {
+		t.Errorf("a YAML scenario should be rejected as such but got %v", err)
+	}
+	if _, err := loadScenario(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("a missing file should be reported but got %v", err)
+	}
+}
+
+func TestFakeServerFaults(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scenario.json")
+	ioutil.WriteFile(path, []byte(`{"faults": {"probabilities": {"rate_limit": 1}, "retryAfter": "3s"}}`), 0600)
+	s, err := loadScenario(path)
+	if err != nil {
+		t.Fatalf("unable to load the scenario: %v", err)
+	}
+	server := httptest.NewServer(newFakeServer(s))
+	defer server.Close()
+	client := recaptcha.NewClient(recaptcha.WithSecret(s.Secret), recaptcha.WithVerifyURL(server.URL+"/recaptcha/api/siteverify"))
+
+	var statusErr *recaptcha.HTTPStatusError
+	if _, err := client.Check(context.Background(), fetchToken(t, server, "site-key", ""), ""); !errors.As(err, &statusErr) ||
+		statusErr.StatusCode != http.StatusTooManyRequests || statusErr.RetryAfter != 3*time.Second {
+		t.Errorf("the request should be rate limited but got %v", err)
+	}
+
+	readCounters := func() map[string]interface{} {
+		resp, err := http.Get(server.URL + "/recaptcha-fake/counters")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var counters map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&counters)
+		return counters
+	}
+	counters := readCounters()
+	faults, _ := counters["faults"].(map[string]interface{})
+	if counters["requests"] != 1.0 || counters["verdicts"] != 0.0 || faults["rate_limit"] != 1.0 {
+		t.Errorf("unexpected counters %v", counters)
+	}
+	resp, err := http.Post(server.URL+"/recaptcha-fake/counters/reset", "", nil)
+	if err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unable to reset the counters: %v", err)
+	}
+	resp.Body.Close()
+	if counters := readCounters(); counters["requests"] != 0.0 {
+		t.Errorf("the counters should be reset but got %v", counters)
+	}
+
+	for _, faults := range []string{`{"probabilities": {"meteor": 0.1}}`, `{"probabilities": {"server_error": 0.7, "html_page": 0.7}}`} {
+		ioutil.WriteFile(path, []byte(`{"faults": `+faults+`}`), 0600)
+		if _, err := loadScenario(path); err == nil {
+			t.Errorf("the faults %s should be rejected", faults)
+		}
+	}
+}
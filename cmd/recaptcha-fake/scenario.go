@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/claudio4/go-recaptcha/recaptchatest"
+)
+
+// duration is a time.Duration written in JSON as a string like "250ms" or as a number of milliseconds
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case float64:
+		*d = duration(time.Duration(value * float64(time.Millisecond)))
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*d = duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	return nil
+}
+
+// outcome is the scripted result of the tokens, every field is optional so the outcomes of the actions
+// only override some fields of the default one
+type outcome struct {
+	Success    *bool    `json:"success"`
+	Score      *float64 `json:"score"`
+	ErrorCodes []string `json:"errorCodes"`
+	Hostname   string   `json:"hostname"`
+	// Latency delays the siteverify answers
+	Latency *duration `json:"latency"`
+}
+
+// merge returns the outcome with the fields set in override replaced
+func (o outcome) merge(override outcome) outcome {
+	if override.Success != nil {
+		o.Success = override.Success
+	}
+	if override.Score != nil {
+		o.Score = override.Score
+	}
+	if override.ErrorCodes != nil {
+		o.ErrorCodes = override.ErrorCodes
+	}
+	if override.Hostname != "" {
+		o.Hostname = override.Hostname
+	}
+	if override.Latency != nil {
+		o.Latency = override.Latency
+	}
+	return o
+}
+
+// scenario configures the fake server, see the package documentation
+type scenario struct {
+	Secret string `json:"secret"`
+	// SolveDelay is how long the widget takes to solve itself
+	SolveDelay duration `json:"solveDelay"`
+	// Default applies to every token
+	Default outcome `json:"default"`
+	// Actions override Default for the tokens of Recaptcha v3 actions
+	Actions map[string]outcome `json:"actions"`
+	// SiteKeys override Default and Actions for the tokens of a site key
+	SiteKeys map[string]outcome `json:"siteKeys"`
+	// Faults (optional) makes siteverify misbehave
+	Faults *faultProfile `json:"faults"`
+}
+
+// faultProfile is the JSON representation of recaptchatest.FaultProfile
+type faultProfile struct {
+	Probabilities map[recaptchatest.Fault]float64 `json:"probabilities"`
+	StatusCode    int                             `json:"statusCode"`
+	RetryAfter    duration                        `json:"retryAfter"`
+	DripInterval  duration                        `json:"dripInterval"`
+	Seed          int64                           `json:"seed"`
+}
+
+func (p faultProfile) validate() error {
+	total := 0.0
+	for fault, probability := range p.Probabilities {
+		switch fault {
+		case recaptchatest.FaultServerError, recaptchatest.FaultRateLimit, recaptchatest.FaultHTMLPage,
+			recaptchatest.FaultTruncatedJSON, recaptchatest.FaultSlowDrip, recaptchatest.FaultConnectionReset:
+		default:
+			return fmt.Errorf("unknown fault %q", fault)
+		}
+		if probability < 0 {
+			return fmt.Errorf("the probability of %s cannot be negative", fault)
+		}
+		total += probability
+	}
+	if total > 1 {
+		return fmt.Errorf("the sum of the fault probabilities is %g, it cannot exceed 1", total)
+	}
+	return nil
+}
+
+func (p faultProfile) profile() recaptchatest.FaultProfile {
+	return recaptchatest.FaultProfile{
+		Probabilities: p.Probabilities,
+		StatusCode:    p.StatusCode,
+		RetryAfter:    time.Duration(p.RetryAfter),
+		DripInterval:  time.Duration(p.DripInterval),
+		Seed:          p.Seed,
+	}
+}
+
+// defaultScenario accepts every token with a 0.9 score
+func defaultScenario() scenario {
+	success, score := true, 0.9
+	return scenario{
+		Secret:  recaptchatest.Secret,
+		Default: outcome{Success: &success, Score: &score},
+	}
+}
+
+// loadScenario reads a JSON scenario file, the fields it does not set keep their default value. YAML is not
+// supported, the error says so for the .yaml and .yml files
+func loadScenario(path string) (scenario, error) {
+	s := defaultScenario()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	override := scenario{}
+	if err := json.Unmarshal(data, &override); err != nil {
+		if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+			return s, fmt.Errorf("invalid scenario %s: only JSON scenarios are supported: %w", path, err)
+		}
+		return s, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	if override.Secret != "" {
+		s.Secret = override.Secret
+	}
+	s.SolveDelay = override.SolveDelay
+	s.Default = s.Default.merge(override.Default)
+	s.Actions = override.Actions
+	s.SiteKeys = override.SiteKeys
+	if override.Faults != nil {
+		if err := override.Faults.validate(); err != nil {
+			return s, fmt.Errorf("invalid scenario %s: %w", path, err)
+		}
+		s.Faults = override.Faults
+	}
+	return s, nil
+}
+
+// verdict returns the siteverify verdict of a token issued for the site key and the action
+func (s scenario) verdict(siteKey, action, hostname string) recaptchatest.Verdict {
+	o := s.Default.merge(s.Actions[action]).merge(s.SiteKeys[siteKey])
+	verdict := recaptchatest.Verdict{
+		Success:    o.Success != nil && *o.Success,
+		Action:     action,
+		Hostname:   hostname,
+		ErrorCodes: o.ErrorCodes,
+	}
+	if o.Hostname != "" {
+		verdict.Hostname = o.Hostname
+	}
+	// only Recaptcha v3 tokens, which have an action, are scored
+	if action != "" && o.Score != nil {
+		verdict.Score = *o.Score
+	}
+	if o.Latency != nil {
+		verdict.Latency = time.Duration(*o.Latency)
+	}
+	return verdict
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	htmltemplate "html/template"
+	"text/template"
+)
+
+// apiTemplate is a stand-in of api.js which renders auto-solving widgets. The widgets are iframes of the anchor
+// page, which posts its token back to the page once solved
+var apiTemplate = template.Must(template.New("api.js").Funcs(template.FuncMap{"js": template.JSEscapeString}).Parse(`(function () {
+  "use strict";
+  var origin = "{{js .Origin}}";
+  var script = document.currentScript;
+  var params = script ? new URL(script.src).searchParams : new URLSearchParams();
+  var widgets = [];
+
+  function call(fn, value) {
+    if (typeof fn === "string") fn = window[fn];
+    if (typeof fn === "function") fn(value);
+  }
+
+  function fetchToken(sitekey, action) {
+    var url = origin + "/recaptcha/api2/token?k=" + encodeURIComponent(sitekey || "") +
+      "&action=" + encodeURIComponent(action || "") + "&host=" + encodeURIComponent(location.hostname);
+    return fetch(url).then(function (r) { return r.json(); }).then(function (body) { return body.token; });
+  }
+
+  function solved(widget, token) {
+    widget.textarea.value = token;
+    call(widget.callback, token);
+  }
+
+  function render(container, options) {
+    if (typeof container === "string") container = document.getElementById(container);
+    options = options || {};
+    var id = widgets.length;
+    var widget = {
+      sitekey: options.sitekey || container.getAttribute("data-sitekey"),
+      callback: options.callback || container.getAttribute("data-callback"),
+      invisible: (options.size || container.getAttribute("data-size")) === "invisible",
+      textarea: document.createElement("textarea")
+    };
+    widget.textarea.name = "g-recaptcha-response";
+    widget.textarea.id = id ? "g-recaptcha-response-" + id : "g-recaptcha-response";
+    widget.textarea.style.display = "none";
+    if (!widget.invisible) {
+      var iframe = document.createElement("iframe");
+      iframe.src = origin + "/recaptcha/api2/anchor?k=" + encodeURIComponent(widget.sitekey || "") +
+        "&host=" + encodeURIComponent(location.hostname) + "&id=" + id;
+      iframe.title = "reCAPTCHA";
+      iframe.width = 304;
+      iframe.height = 78;
+      iframe.style.border = "none";
+      container.appendChild(iframe);
+    }
+    container.appendChild(widget.textarea);
+    widgets.push(widget);
+    return id;
+  }
+
+  window.addEventListener("message", function (event) {
+    var data = event.data;
+    if (event.origin !== origin || !data || data.type !== "recaptcha-fake-solved" || !widgets[data.id]) return;
+    solved(widgets[data.id], data.token);
+  });
+
+  var grecaptcha = {
+    render: render,
+    ready: function (fn) { setTimeout(fn, 0); },
+    execute: function (sitekey, options) {
+      if (typeof sitekey === "string") {
+        return fetchToken(sitekey, options && options.action);
+      }
+      var widget = widgets[sitekey || 0];
+      if (!widget) return Promise.reject(new Error("unknown widget"));
+      return fetchToken(widget.sitekey, "").then(function (token) { solved(widget, token); return token; });
+    },
+    getResponse: function (id) {
+      var widget = widgets[id || 0];
+      return widget ? widget.textarea.value : "";
+    },
+    reset: function (id) {
+      var widget = widgets[id || 0];
+      if (widget) widget.textarea.value = "";
+    }
+  };
+  grecaptcha.enterprise = grecaptcha;
+  window.grecaptcha = grecaptcha;
+
+  function onReady() {
+    var mode = params.get("render");
+    if (!mode || mode === "onload") {
+      Array.prototype.forEach.call(document.querySelectorAll(".g-recaptcha"), function (el) { render(el); });
+    }
+    if (params.get("onload")) call(params.get("onload"));
+  }
+  if (document.readyState === "loading") {
+    document.addEventListener("DOMContentLoaded", onReady);
+  } else {
+    onReady();
+  }
+})();
+`))
+
+// anchorTemplate is the page of a widget, it checks itself after the solve delay of the scenario
+var anchorTemplate = htmltemplate.Must(htmltemplate.New("anchor").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>reCAPTCHA</title></head>
+<body style="margin:0;font-family:Roboto,Arial,sans-serif">
+<div style="box-sizing:border-box;width:302px;height:76px;border:1px solid #d3d3d3;border-radius:3px;background:#f9f9f9;display:flex;align-items:center;padding:0 12px">
+<input type="checkbox" id="recaptcha-anchor" aria-label="I'm not a robot" disabled>
+<label for="recaptcha-anchor" style="margin-left:12px;font-size:14px">I'm not a robot</label>
+</div>
+<script>
+setTimeout(function () {
+  document.getElementById("recaptcha-anchor").checked = true;
+  parent.postMessage({type: "recaptcha-fake-solved", id: {{.ID}}, token: {{.Token}}}, "*");
+}, {{.DelayMs}});
+</script>
+</body>
+</html>
+`))
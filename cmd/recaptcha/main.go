@@ -0,0 +1,437 @@
+// Command recaptcha verifies user responses and diagnoses secrets against the siteverify endpoint, without
+// crafting the requests by hand. Usage:
+//
+//	recaptcha verify [flags] TOKEN
+//	recaptcha check-secret [flags]
+//	recaptcha parse-ts [-json] TIMESTAMP...
+//
+// The secret is read from the RECAPTCHA_SECRET environment variable (see -secret-env) or from the file given
+// with -secret-file, it cannot be given on the command line so it does not end up in the shell history. The
+// token of verify is read from the standard input when it is "-".
+//
+// verify prints the verdict of the token, as text or as JSON with -json. It checks a Recaptcha v3 token with -v3,
+// which -action and -min-score imply. check-secret sends a dummy token and tells whether the API rejected the
+// secret (invalid-input-secret) or only the token (invalid-input-response). parse-ts prints the challenge
+// timestamps in UTC along with their age.
+//
+// The exit code is 0 when the token is valid, the secret is accepted or the timestamps are parsed, 1 when they
+// are not, 2 on usage errors and 3 when the API could not be reached or answered unexpectedly.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+// The exit codes of the command
+const (
+	exitOK       = 0
+	exitRejected = 1
+	exitUsage    = 2
+	exitFailure  = 3
+)
+
+// checkSecretToken is the dummy token sent by check-secret
+const checkSecretToken = "recaptcha-cli-check-secret"
+
+func main() {
+	cli := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr, getenv: os.Getenv, now: time.Now}
+	os.Exit(cli.run(os.Args[1:]))
+}
+
+// cli holds the environment of the command, so the tests can replace it
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	getenv         func(string) string
+	now            func() time.Time
+}
+
+const usage = `Usage:
+  recaptcha verify [flags] TOKEN     verify a user response ("-" reads it from stdin)
+  recaptcha check-secret [flags]     tell whether the API accepts the secret
+  recaptcha parse-ts [-json] TS...   parse challenge timestamps
+
+Run "recaptcha COMMAND -h" for the flags of a command.
+`
+
+func (c *cli) run(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(c.stderr, usage)
+		return exitUsage
+	}
+	switch args[0] {
+	case "verify":
+		return c.verify(args[1:])
+	case "check-secret":
+		return c.checkSecret(args[1:])
+	case "parse-ts":
+		return c.parseTimeStamps(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(c.stdout, usage)
+		return exitOK
+	}
+	fmt.Fprintf(c.stderr, "unknown command %q\n%s", args[0], usage)
+	return exitUsage
+}
+
+// apiFlags are the flags of the commands calling the API
+type apiFlags struct {
+	secretEnv  string
+	secretFile string
+	verifyURL  string
+	timeout    time.Duration
+	json       bool
+}
+
+func (f *apiFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.secretEnv, "secret-env", "RECAPTCHA_SECRET", "environment variable holding the secret")
+	flags.StringVar(&f.secretFile, "secret-file", "", "file holding the secret, overrides -secret-env")
+	flags.StringVar(&f.verifyURL, "url", recaptcha.DefaultVerifyURL, "siteverify endpoint")
+	flags.DurationVar(&f.timeout, "timeout", 10*time.Second, "timeout of the request")
+	flags.BoolVar(&f.json, "json", false, "print the result as JSON")
+}
+
+// secret returns the secret from the file or the environment variable
+func (c *cli) secret(f *apiFlags) (string, error) {
+	if f.secretFile != "" {
+		content, err := ioutil.ReadFile(f.secretFile)
+		if err != nil {
+			return "", err
+		}
+		if secret := strings.TrimSpace(string(content)); secret != "" {
+			return secret, nil
+		}
+		return "", fmt.Errorf("the secret file %s is empty", f.secretFile)
+	}
+	if secret := strings.TrimSpace(c.getenv(f.secretEnv)); secret != "" {
+		return secret, nil
+	}
+	return "", fmt.Errorf("no secret: set the %s environment variable or use -secret-file", f.secretEnv)
+}
+
+// client creates the client of the commands calling the API
+func (c *cli) client(f *apiFlags, secret string, opts ...recaptcha.Option) *recaptcha.Client {
+	opts = append([]recaptcha.Option{
+		recaptcha.WithSecret(secret),
+		recaptcha.WithVerifyURL(f.verifyURL),
+		recaptcha.WithHTTPClient(&http.Client{Timeout: f.timeout}),
+		recaptcha.WithClock(c.now),
+	}, opts...)
+	return recaptcha.NewClient(opts...)
+}
+
+func (c *cli) newFlagSet(name, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: recaptcha %s [flags] %s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// verification is the JSON output of verify
+type verification struct {
+	Success            bool     `json:"success"`
+	ChallengeTimeStamp string   `json:"challenge_ts,omitempty"`
+	Hostname           string   `json:"hostname,omitempty"`
+	ApkPackageName     string   `json:"apk_package_name,omitempty"`
+	Score              *float64 `json:"score,omitempty"`
+	Action             string   `json:"action,omitempty"`
+	ErrorCodes         []string `json:"error-codes,omitempty"`
+	Attempts           int      `json:"attempts,omitempty"`
+}
+
+func (c *cli) verify(args []string) int {
+	var api apiFlags
+	flags := c.newFlagSet("verify", "TOKEN")
+	api.register(flags)
+	remoteIP := flags.String("remote-ip", "", "IP address of the user (optional)")
+	v3 := flags.Bool("v3", false, "verify a Recaptcha v3 token and print its score and action")
+	action := flags.String("action", "", "expected action of the v3 token (implies -v3)")
+	minScore := flags.Float64("min-score", 0, "minimum score of the v3 token (implies -v3)")
+	hostnames := flags.String("hostname", "", "comma separated hostnames allowed to solve the captcha (optional)")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	token, err := c.token(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitUsage
+	}
+	secret, err := c.secret(&api)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitUsage
+	}
+
+	var opts []recaptcha.Option
+	if *hostnames != "" {
+		opts = append(opts, recaptcha.WithAllowedHostnames(strings.Split(*hostnames, ",")...))
+	}
+	if *action != "" || *minScore != 0 {
+		*v3 = true
+		opts = append(opts, recaptcha.WithV3Policy(recaptcha.V3Policy{Action: *action, Threshold: *minScore}))
+	}
+	client := c.client(&api, secret, opts...)
+	ctx, cancel := context.WithTimeout(context.Background(), api.timeout)
+	defer cancel()
+
+	var response recaptcha.ResponseV3
+	if *v3 {
+		response, err = client.CheckV3(ctx, token, *remoteIP)
+	} else {
+		response.Response, err = client.Check(ctx, token, *remoteIP)
+	}
+	if err != nil {
+		fmt.Fprintf(c.stderr, "unable to verify the token: %v\n", err)
+		return exitFailure
+	}
+
+	result := verification{
+		Success:            response.Success,
+		ChallengeTimeStamp: response.ChallengeTimeStamp,
+		Hostname:           response.Hostname,
+		ApkPackageName:     response.ApkPackageName,
+		ErrorCodes:         errorCodes(response.Errors),
+		Attempts:           response.Attempts,
+	}
+	if *v3 {
+		result.Score, result.Action = &response.Score, response.Action
+	}
+	if api.json {
+		c.printJSON(result)
+	} else {
+		c.printVerification(result, response.Errors)
+	}
+
+	if !response.Success {
+		return exitRejected
+	}
+	return exitOK
+}
+
+// token returns the token argument, reading it from stdin when it is "-"
+func (c *cli) token(arg string) (string, error) {
+	if arg != "-" {
+		return arg, nil
+	}
+	line, err := bufio.NewReader(c.stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("unable to read the token: %w", err)
+	}
+	if token := strings.TrimSpace(line); token != "" {
+		return token, nil
+	}
+	return "", errors.New("no token on the standard input")
+}
+
+func (c *cli) printVerification(result verification, errs recaptcha.Errors) {
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintf(w, "success:\t%t\n", result.Success)
+	if result.ChallengeTimeStamp != "" {
+		fmt.Fprintf(w, "challenge_ts:\t%s\n", result.ChallengeTimeStamp)
+	}
+	if result.Hostname != "" {
+		fmt.Fprintf(w, "hostname:\t%s\n", result.Hostname)
+	}
+	if result.ApkPackageName != "" {
+		fmt.Fprintf(w, "apk_package_name:\t%s\n", result.ApkPackageName)
+	}
+	if result.Score != nil {
+		fmt.Fprintf(w, "score:\t%.1f\n", *result.Score)
+		fmt.Fprintf(w, "action:\t%s\n", result.Action)
+	}
+	for i, err := range errs {
+		label := ""
+		if i == 0 {
+			label = "errors:"
+		}
+		if code := result.ErrorCodes[i]; code != err.Error() {
+			fmt.Fprintf(w, "%s\t%s (%v)\n", label, code, err)
+		} else {
+			fmt.Fprintf(w, "%s\t%v\n", label, err)
+		}
+	}
+}
+
+// errorCodes returns the API error codes of the errors, the errors without code are described by their message
+func errorCodes(errs recaptcha.Errors) []string {
+	codes := make([]string, len(errs))
+	for i, err := range errs {
+		codes[i] = errorCode(err)
+	}
+	return codes
+}
+
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, recaptcha.ErrInvalidInputResponse):
+		return "invalid-input-response"
+	case errors.Is(err, recaptcha.ErrInvalidInputSecret):
+		return "invalid-input-secret"
+	case errors.Is(err, recaptcha.ErrTimeoutOrDuplicate):
+		return "timeout-or-duplicate"
+	case errors.Is(err, recaptcha.ErrBadRequest):
+		return "bad-request"
+	case errors.Is(err, recaptcha.ErrInternalError):
+		return "internal-error"
+	case errors.Is(err, recaptcha.ErrHostnameMismatch):
+		return "hostname-mismatch"
+	case errors.Is(err, recaptcha.ErrChallengeExpired):
+		return "challenge-expired"
+	case errors.Is(err, recaptcha.ErrActionMismatch):
+		return "action-mismatch"
+	case errors.Is(err, recaptcha.ErrScoreTooLow):
+		return "score-too-low"
+	}
+	return err.Error()
+}
+
+// secretCheck is the JSON output of check-secret
+type secretCheck struct {
+	Valid      bool     `json:"valid"`
+	Detail     string   `json:"detail"`
+	ErrorCodes []string `json:"error-codes,omitempty"`
+}
+
+func (c *cli) checkSecret(args []string) int {
+	var api apiFlags
+	flags := c.newFlagSet("check-secret", "")
+	api.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return exitUsage
+	}
+	secret, err := c.secret(&api)
+	if err != nil {
+		fmt.Fprintln(c.stderr, err)
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), api.timeout)
+	defer cancel()
+	response, err := c.client(&api, secret).Check(ctx, checkSecretToken, "")
+	if err != nil {
+		fmt.Fprintf(c.stderr, "unable to check the secret: %v\n", err)
+		return exitFailure
+	}
+
+	// the API reports the secret before the token, so a rejected token means the secret was accepted
+	result := secretCheck{ErrorCodes: errorCodes(response.Errors)}
+	code := exitOK
+	switch {
+	case hasError(response.Errors, recaptcha.ErrInvalidInputSecret):
+		result.Detail = "the secret was rejected (invalid-input-secret)"
+		code = exitRejected
+	case response.Success:
+		result.Valid = true
+		result.Detail = "the secret accepts any token, it is probably a test secret"
+	case hasError(response.Errors, recaptcha.ErrInvalidInputResponse), hasError(response.Errors, recaptcha.ErrTimeoutOrDuplicate):
+		result.Valid = true
+		result.Detail = "the secret was accepted, only the dummy token was rejected"
+	default:
+		result.Detail = "unexpected answer of the API"
+		code = exitFailure
+	}
+
+	if api.json {
+		c.printJSON(result)
+	} else {
+		fmt.Fprintln(c.stdout, result.Detail)
+		if code == exitFailure {
+			fmt.Fprintf(c.stdout, "error codes: %s\n", strings.Join(result.ErrorCodes, ", "))
+		}
+	}
+	return code
+}
+
+func hasError(errs recaptcha.Errors, target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsedTimeStamp is the JSON output of parse-ts
+type parsedTimeStamp struct {
+	Input string `json:"input"`
+	UTC   string `json:"utc,omitempty"`
+	Unix  int64  `json:"unix,omitempty"`
+	// Age is negative for timestamps in the future
+	Age   string `json:"age,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+func (c *cli) parseTimeStamps(args []string) int {
+	flags := c.newFlagSet("parse-ts", "TIMESTAMP...")
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	code := exitOK
+	results := make([]parsedTimeStamp, flags.NArg())
+	for i, ts := range flags.Args() {
+		results[i].Input = ts
+		t, err := recaptcha.ParseTimeStamp(ts)
+		if err != nil {
+			results[i].Error = err.Error()
+			code = exitRejected
+			continue
+		}
+		results[i].UTC = t.UTC().Format(time.RFC3339Nano)
+		results[i].Unix = t.Unix()
+		results[i].Age = c.now().Sub(t).Round(time.Second).String()
+	}
+
+	if *asJSON {
+		c.printJSON(results)
+		return code
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+	for _, result := range results {
+		if result.Error != "" {
+			fmt.Fprintf(w, "%s\tinvalid: %s\n", result.Input, result.Error)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\tunix %d\tage %s\n", result.Input, result.UTC, result.Unix, result.Age)
+		}
+	}
+	return code
+}
+
+func (c *cli) printJSON(v interface{}) {
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
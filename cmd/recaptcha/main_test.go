@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claudio4/go-recaptcha/recaptchatest"
+)
+
+var testNow = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// runCLI runs the command with the secret in RECAPTCHA_SECRET and returns its exit code and outputs
+func runCLI(secret, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	c := &cli{
+		stdin:  strings.NewReader(stdin),
+		stdout: &stdout,
+		stderr: &stderr,
+		getenv: func(name string) string {
+			if name == "RECAPTCHA_SECRET" {
+				return secret
+			}
+			return ""
+		},
+		now: func() time.Time { return testNow },
+	}
+	code := c.run(args)
+	return code, stdout.String(), stderr.String()
+}
+
+// newSiteverify starts a fake siteverify which knows the "human" token, it must be closed by the caller
+func newSiteverify() *recaptchatest.Server {
+	server := recaptchatest.NewServer()
+	server.Register("human", recaptchatest.Verdict{Success: true, Score: 0.9, Action: "login", Hostname: "example.com",
+		ChallengeTimeStamp: testNow.Add(-time.Minute), Reusable: true})
+	return server
+}
+
+func TestVerify(t *testing.T) {
+	server := newSiteverify()
+	defer server.Close()
+
+	code, stdout, stderr := runCLI(recaptchatest.Secret, "", "verify", "-url", server.URL, "-remote-ip", "10.0.0.1", "human")
+	if code != exitOK || !strings.Contains(stdout, "success:       true") || !strings.Contains(stdout, "hostname:      example.com") ||
+		strings.Contains(stdout, "score") {
+		t.Errorf("unexpected result %d:\n%s%s", code, stdout, stderr)
+	}
+
+	code, stdout, _ = runCLI(recaptchatest.Secret, "human\n", "verify", "-url", server.URL, "-v3", "-json", "-")
+	var result verification
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", stdout, err)
+	}
+	if code != exitOK || !result.Success || result.Score == nil || *result.Score != 0.9 || result.Action != "login" ||
+		result.ChallengeTimeStamp != "2024-05-01T11:59:00Z" {
+		t.Errorf("unexpected result %d %+v", code, result)
+	}
+
+	code, stdout, _ = runCLI(recaptchatest.Secret, "", "verify", "-url", server.URL, "-json", "-min-score", "0.95", "human")
+	result = verification{}
+	json.Unmarshal([]byte(stdout), &result)
+	if code != exitRejected || result.Success || len(result.ErrorCodes) != 1 || result.ErrorCodes[0] != "score-too-low" {
+		t.Errorf("the policy should reject the token but got %d %+v", code, result)
+	}
+
+	code, stdout, _ = runCLI(recaptchatest.Secret, "", "verify", "-url", server.URL, "forged")
+	if code != exitRejected || !strings.Contains(stdout, "success:  false") || !strings.Contains(stdout, "errors:   invalid-input-response (") {
+		t.Errorf("the forged token should be rejected but got %d:\n%s", code, stdout)
+	}
+}
+
+func TestVerifyFailures(t *testing.T) {
+	server := newSiteverify()
+	defer server.Close()
+
+	if code, _, stderr := runCLI("", "", "verify", "-url", server.URL, "human"); code != exitUsage || !strings.Contains(stderr, "RECAPTCHA_SECRET") {
+		t.Errorf("a missing secret should be a usage error but got %d %q", code, stderr)
+	}
+	if code, _, _ := runCLI(recaptchatest.Secret, "", "verify", "-url", server.URL); code != exitUsage {
+		t.Errorf("a missing token should be a usage error but got %d", code)
+	}
+	if code, _, _ := runCLI(recaptchatest.Secret, "\n", "verify", "-url", server.URL, "-"); code != exitUsage {
+		t.Errorf("an empty standard input should be a usage error but got %d", code)
+	}
+	if code, _, _ := runCLI(recaptchatest.Secret, "", "frobnicate"); code != exitUsage {
+		t.Errorf("an unknown command should be a usage error but got %d", code)
+	}
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	if code, _, stderr := runCLI(recaptchatest.Secret, "", "verify", "-url", down.URL, "human"); code != exitFailure || !strings.Contains(stderr, "503") {
+		t.Errorf("an outage should be a failure but got %d %q", code, stderr)
+	}
+}
+
+func TestCheckSecret(t *testing.T) {
+	server := newSiteverify()
+	defer server.Close()
+
+	if code, stdout, _ := runCLI(recaptchatest.Secret, "", "check-secret", "-url", server.URL); code != exitOK || !strings.Contains(stdout, "was accepted") {
+		t.Errorf("the secret should be accepted but got %d %q", code, stdout)
+	}
+	if code, stdout, _ := runCLI("wrong", "", "check-secret", "-url", server.URL); code != exitRejected || !strings.Contains(stdout, "invalid-input-secret") {
+		t.Errorf("the secret should be rejected but got %d %q", code, stdout)
+	}
+
+	secretFile, err := ioutil.TempFile("", "recaptcha-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(secretFile.Name())
+	secretFile.WriteString(recaptchatest.Secret + "\n")
+	secretFile.Close()
+	code, stdout, _ := runCLI("wrong", "", "check-secret", "-url", server.URL, "-secret-file", secretFile.Name(), "-json")
+	var result secretCheck
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil || code != exitOK || !result.Valid {
+		t.Errorf("the secret of the file should be accepted but got %d %q", code, stdout)
+	}
+
+	server.SetDefault(recaptchatest.Verdict{Success: true})
+	if code, stdout, _ := runCLI(recaptchatest.Secret, "", "check-secret", "-url", server.URL); code != exitOK || !strings.Contains(stdout, "test secret") {
+		t.Errorf("a test secret should be detected but got %d %q", code, stdout)
+	}
+	server.SetDefault(recaptchatest.Verdict{ErrorCodes: []string{"bad-request"}})
+	if code, _, _ := runCLI(recaptchatest.Secret, "", "check-secret", "-url", server.URL); code != exitFailure {
+		t.Errorf("an unexpected answer should be a failure but got %d", code)
+	}
+}
+
+func TestParseTimeStamps(t *testing.T) {
+	code, stdout, _ := runCLI("", "", "parse-ts", "2024-05-01T13:55:00+0200")
+	if code != exitOK || strings.TrimSpace(stdout) != "2024-05-01T13:55:00+0200  2024-05-01T11:55:00Z  unix 1714564500  age 5m0s" {
+		t.Errorf("unexpected result %d %q", code, stdout)
+	}
+
+	code, stdout, _ = runCLI("", "", "parse-ts", "-json", "2024-05-01T12:00:30Z", "yesterday")
+	var results []parsedTimeStamp
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", stdout, err)
+	}
+	if code != exitRejected || len(results) != 2 || results[0].Age != "-30s" || results[1].Error == "" {
+		t.Errorf("unexpected result %d %+v", code, results)
+	}
+}
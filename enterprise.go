@@ -0,0 +1,286 @@
+package recaptcha
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// DefaultEnterpriseURL is the base URL of the reCAPTCHA Enterprise API
+const DefaultEnterpriseURL = "https://recaptchaenterprise.googleapis.com/v1"
+
+// Enterprise verifies user responses with the reCAPTCHA Enterprise createAssessment API.
+// Its results embed ResponseV3, so the code written for Client.CheckV3 works with it too
+type Enterprise struct {
+	client  *Client
+	project string
+	siteKey string
+	apiKey  string
+	baseURL string
+
+	tokenSource TokenSource
+}
+
+var _ Verifier = (*Enterprise)(nil)
+
+// EnterpriseOption configures an Enterprise client, see NewEnterprise
+type EnterpriseOption func(*Enterprise)
+
+// WithAPIKey sets the API key used to authenticate the API calls
+func WithAPIKey(apiKey string) EnterpriseOption {
+	return func(e *Enterprise) {
+		e.apiKey = apiKey
+	}
+}
+
+// WithEnterpriseURL overrides the base URL of the API (DefaultEnterpriseURL by default)
+func WithEnterpriseURL(baseURL string) EnterpriseOption {
+	return func(e *Enterprise) {
+		e.baseURL = baseURL
+	}
+}
+
+// WithClientOptions configures the Client used underneath, which handles the HTTP client, the user agent,
+// retries, the circuit breaker, replay protection and the checks made on the verdicts
+// (WithAllowedHostnames, WithMaxChallengeAge, WithV3Policy...). The secret and the verify URL are ignored
+func WithClientOptions(opts ...Option) EnterpriseOption {
+	return func(e *Enterprise) {
+		for _, opt := range opts {
+			opt(e.client)
+		}
+	}
+}
+
+// NewEnterprise creates a reCAPTCHA Enterprise client for the given Google Cloud project and site key
+func NewEnterprise(project, siteKey string, opts ...EnterpriseOption) *Enterprise {
+	e := &Enterprise{
+		client:  NewClient(),
+		project: project,
+		siteKey: siteKey,
+		baseURL: DefaultEnterpriseURL,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Event describes the user interaction being assessed
+type Event struct {
+	// Token is the user response token provided by the reCAPTCHA client-side integration of your app
+	Token string `json:"token,omitempty"`
+	// SiteKey is the key used to generate the token, the one of the client is used if empty
+	SiteKey string `json:"siteKey,omitempty"`
+	// ExpectedAction (optional) is the action the token should have been generated for
+	ExpectedAction string `json:"expectedAction,omitempty"`
+	// UserIPAddress (optional) is the user's IP
+	UserIPAddress string `json:"userIpAddress,omitempty"`
+	// UserAgent (optional) is the user agent of the user's browser
+	UserAgent string `json:"userAgent,omitempty"`
+	// HashedAccountID (optional) is the hash of the account ID of the user for Account Defender, see HashAccountID
+	HashedAccountID []byte `json:"hashedAccountId,omitempty"`
+	// UserInfo (optional) identifies the account of the user for Account Defender
+	UserInfo *UserInfo `json:"userInfo,omitempty"`
+	// TransactionData (optional) describes the payment of the event for fraud prevention, it is validated
+	// before the assessment is created
+	TransactionData *TransactionData `json:"transactionData,omitempty"`
+	// RequestedURI (optional) is the URL requested by the user, it is needed by FirewallPolicyEvaluation
+	RequestedURI string `json:"requestedUri,omitempty"`
+	// WAFTokenAssessment tells that Token is a WAF token, see WAFToken
+	WAFTokenAssessment bool `json:"wafTokenAssessment,omitempty"`
+	// FirewallPolicyEvaluation asks the API to evaluate the firewall policies of the site against the event
+	FirewallPolicyEvaluation bool `json:"firewallPolicyEvaluation,omitempty"`
+}
+
+// EnterpriseResponse represents a reCAPTCHA Enterprise assessment.
+// Success, Score, Action, Hostname and ChallengeTimeStamp are filled from the token properties and the
+// risk analysis, and the reason of an invalid token is reported in Errors (e.g. EXPIRED is ErrTimeoutOrDuplicate)
+type EnterpriseResponse struct {
+	ResponseV3
+	// Name is the resource name of the assessment, it is needed to annotate it (see Enterprise.Annotate)
+	Name AssessmentName
+	// Reasons explain the risk analysis score, read more at
+	// https://cloud.google.com/recaptcha-enterprise/docs/reference/rest/v1/RiskAnalysis#classificationreason
+	Reasons []string
+	// InvalidReason is the reason given by the API for an invalid token
+	InvalidReason string
+	// AccountDefenderLabels are the labels given by Account Defender to the account of the event
+	AccountDefenderLabels []AccountDefenderLabel
+	// FraudPrevention is the fraud risk analysis of the transaction, it is nil when the event has no TransactionData
+	FraudPrevention *FraudPreventionAssessment
+	// Firewall is the result of the firewall policy evaluation, it is nil unless Event.FirewallPolicyEvaluation is set
+	Firewall *FirewallPolicyAssessment
+}
+
+// assessment is the wire representation of an assessment
+type assessment struct {
+	Name            AssessmentName  `json:"name,omitempty"`
+	Event           *Event          `json:"event,omitempty"`
+	RiskAnalysis    riskAnalysis    `json:"riskAnalysis"`
+	TokenProperties tokenProperties `json:"tokenProperties"`
+
+	AccountDefenderAssessment       *accountDefenderAssessment `json:"accountDefenderAssessment,omitempty"`
+	FraudPreventionAssessment       *FraudPreventionAssessment `json:"fraudPreventionAssessment,omitempty"`
+	FirewallPolicyAssessment        *FirewallPolicyAssessment  `json:"firewallPolicyAssessment,omitempty"`
+	PrivatePasswordLeakVerification *passwordLeakVerification  `json:"privatePasswordLeakVerification,omitempty"`
+}
+
+type riskAnalysis struct {
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+type tokenProperties struct {
+	Valid              bool   `json:"valid"`
+	InvalidReason      string `json:"invalidReason"`
+	Hostname           string `json:"hostname"`
+	AndroidPackageName string `json:"androidPackageName"`
+	Action             string `json:"action"`
+	CreateTime         string `json:"createTime"`
+}
+
+// Check verifies a user response, see Client.Check
+func (e *Enterprise) Check(ctx context.Context, clientResponse, remoteIP string) (Response, error) {
+	response, err := e.Assess(ctx, Event{Token: clientResponse, UserIPAddress: remoteIP})
+	return response.Response, err
+}
+
+// CheckV3 verifies a user response, see Client.CheckV3
+func (e *Enterprise) CheckV3(ctx context.Context, clientResponse, remoteIP string) (ResponseV3, error) {
+	response, err := e.Assess(ctx, Event{Token: clientResponse, UserIPAddress: remoteIP})
+	return response.ResponseV3, err
+}
+
+// FormField returns DefaultFormField
+func (e *Enterprise) FormField() string {
+	return e.client.formField
+}
+
+// RemoteIP returns the user's IP of a request, see Client.RemoteIP
+func (e *Enterprise) RemoteIP(r *http.Request) string {
+	return e.client.RemoteIP(r)
+}
+
+// Assess creates an assessment of the event. Like Client.Check, transport and protocol failures are reported
+// through err while the problems with the token are reported in response.Errors
+func (e *Enterprise) Assess(ctx context.Context, event Event) (response EnterpriseResponse, err error) {
+	if event.TransactionData != nil {
+		if err := event.TransactionData.Validate(); err != nil {
+			return response, err
+		}
+	}
+	if event.Token == "" {
+		response.Errors = []error{ErrInvalidInputResponse}
+		return response, nil
+	}
+	if event.SiteKey == "" {
+		event.SiteKey = e.siteKey
+	}
+
+	err = e.client.protect(ctx, event.Token, event.UserIPAddress, eventFingerprint(event), &response, func() error {
+		return e.createAssessment(ctx, event, &response)
+	})
+	return response, err
+}
+
+// eventFingerprint identifies the parameters of an event, so the idempotency cache only reuses the verdicts
+// of identical assessments
+func eventFingerprint(event Event) string {
+	fingerprint, _ := json.Marshal(event)
+	return string(fingerprint)
+}
+
+func (e *Enterprise) createAssessment(ctx context.Context, event Event, response *EnterpriseResponse) error {
+	var result assessment
+	attempts, err := e.post(ctx, "projects/"+url.PathEscape(e.project)+"/assessments", assessment{Event: &event}, &result)
+	response.Attempts = attempts
+	if err != nil {
+		if e.client.degrade(err, response) {
+			return nil
+		}
+		return err
+	}
+
+	response.fill(result)
+	if event.ExpectedAction != "" && response.Success && response.Action != event.ExpectedAction {
+		response.Success = false
+		response.Errors = append(response.Errors, &ActionMismatchError{Expected: event.ExpectedAction, Actual: response.Action})
+	}
+	e.client.checkVerdict(response)
+	return nil
+}
+
+// fill copies an assessment into the response
+func (r *EnterpriseResponse) fill(result assessment) {
+	r.Name = result.Name
+	r.Success = result.TokenProperties.Valid
+	r.ChallengeTimeStamp = result.TokenProperties.CreateTime
+	r.Hostname = result.TokenProperties.Hostname
+	r.ApkPackageName = result.TokenProperties.AndroidPackageName
+	r.Action = result.TokenProperties.Action
+	r.Score = result.RiskAnalysis.Score
+	r.Reasons = result.RiskAnalysis.Reasons
+	if result.AccountDefenderAssessment != nil {
+		r.AccountDefenderLabels = result.AccountDefenderAssessment.Labels
+	}
+	r.FraudPrevention = result.FraudPreventionAssessment
+	r.Firewall = result.FirewallPolicyAssessment
+	if !result.TokenProperties.Valid {
+		r.InvalidReason = result.TokenProperties.InvalidReason
+		r.Errors = []error{invalidReasonError(r.InvalidReason)}
+	}
+}
+
+// invalidReasonError maps the reason of an invalid token to the errors of this package
+func invalidReasonError(reason string) error {
+	switch reason {
+	case "MALFORMED", "MISSING":
+		return ErrInvalidInputResponse
+	case "EXPIRED", "DUPE":
+		return ErrTimeoutOrDuplicate
+	case "SITE_MISMATCH":
+		return ErrSiteMismatch
+	case "BROWSER_ERROR":
+		return ErrBrowserError
+	case "", "INVALID_REASON_UNSPECIFIED", "UNKNOWN_INVALID_REASON":
+		return ErrUnknownInvalidReason
+	}
+	return errors.New(reason)
+}
+
+// post sends a JSON request to the API and decodes its JSON response into result
+func (e *Enterprise) post(ctx context.Context, path string, payload, result interface{}) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	endpoint := e.baseURL + "/" + path
+	if e.apiKey != "" {
+		endpoint += "?key=" + url.QueryEscape(e.apiKey)
+	}
+	// the token is fetched outside of the retries and the circuit breaker, a credentials problem
+	// says nothing about the health of the API
+	var authorization string
+	if e.tokenSource != nil {
+		token, err := e.tokenSource.Token(ctx)
+		if err != nil {
+			return 0, &AuthError{Err: err}
+		}
+		authorization = "Bearer " + token.AccessToken
+	}
+
+	return e.client.call(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		return req, nil
+	}, result)
+}
@@ -0,0 +1,140 @@
+package recaptcha_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+const (
+	enterpriseProject = "my-project"
+	enterpriseSiteKey = "6LcSiteKey"
+	enterpriseAPIKey  = "AIzaKey"
+)
+
+// enterpriseServer is a stand-in of the reCAPTCHA Enterprise API, handler gets the decoded request body
+func enterpriseServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, body map[string]interface{})) *httptest.Server {
+	t.Helper()
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s with Content-Type %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("the request body should be valid JSON: %v", err)
+		}
+		handler(w, r, body)
+	})
+	return server
+}
+
+func assessmentReply(t *testing.T, reply string) func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+	return func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+		if r.URL.Path != "/projects/"+enterpriseProject+"/assessments" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if key := r.URL.Query().Get("key"); key != enterpriseAPIKey {
+			t.Errorf("the API key should be %s but it was %s", enterpriseAPIKey, key)
+		}
+		event, _ := body["event"].(map[string]interface{})
+		if event["token"] != gResponse || event["siteKey"] != enterpriseSiteKey {
+			t.Errorf("unexpected event %v", event)
+		}
+		jsonReply(reply)(w, r)
+	}
+}
+
+func newTestEnterprise(baseURL string, opts ...recaptcha.Option) *recaptcha.Enterprise {
+	return recaptcha.NewEnterprise(enterpriseProject, enterpriseSiteKey,
+		recaptcha.WithAPIKey(enterpriseAPIKey),
+		recaptcha.WithEnterpriseURL(baseURL),
+		recaptcha.WithClientOptions(opts...),
+	)
+}
+
+func TestEnterpriseAssess(t *testing.T) {
+	server := enterpriseServer(t, assessmentReply(t, `{
+		"name": "projects/my-project/assessments/b6ac310000000000",
+		"event": {"token": "ABCDEF", "siteKey": "6LcSiteKey", "expectedAction": "login"},
+		"riskAnalysis": {"score": 0.9, "reasons": ["LOW_CONFIDENCE_SCORE"]},
+		"tokenProperties": {"valid": true, "invalidReason": "INVALID_REASON_UNSPECIFIED", "hostname": "example.com",
+			"action": "login", "createTime": "2020-08-16T12:18:29.123456Z"}
+	}`))
+	defer server.Close()
+	enterprise := newTestEnterprise(server.URL)
+	resp, err := enterprise.Assess(context.Background(), recaptcha.Event{Token: gResponse, ExpectedAction: "login", UserIPAddress: clientIP})
+	if err != nil || !resp.Success {
+		t.Fatalf("the assessment should be successful but got %+v, %v", resp, err)
+	}
+	if resp.Name != "projects/my-project/assessments/b6ac310000000000" || resp.Score != 0.9 || resp.Action != "login" ||
+		resp.Hostname != "example.com" || len(resp.Reasons) != 1 {
+		t.Errorf("the assessment was not decoded: %+v", resp)
+	}
+	if _, err := recaptcha.ParseTimeStamp(resp.ChallengeTimeStamp); err != nil {
+		t.Errorf("the token creation time should be a valid timestamp: %v", err)
+	}
+
+	// the expected action is enforced
+	resp, _ = enterprise.Assess(context.Background(), recaptcha.Event{Token: gResponse, ExpectedAction: "signup"})
+	if resp.Success || len(resp.Errors) != 1 || !errors.Is(resp.Errors[0], recaptcha.ErrActionMismatch) {
+		t.Errorf("the errors array should contain ErrActionMismatch but it contains: %+v", resp.Errors)
+	}
+}
+
+func TestEnterpriseInvalidReasons(t *testing.T) {
+	cases := map[string]error{
+		"MALFORMED":              recaptcha.ErrInvalidInputResponse,
+		"MISSING":                recaptcha.ErrInvalidInputResponse,
+		"EXPIRED":                recaptcha.ErrTimeoutOrDuplicate,
+		"DUPE":                   recaptcha.ErrTimeoutOrDuplicate,
+		"SITE_MISMATCH":          recaptcha.ErrSiteMismatch,
+		"BROWSER_ERROR":          recaptcha.ErrBrowserError,
+		"UNKNOWN_INVALID_REASON": recaptcha.ErrUnknownInvalidReason,
+	}
+	for reason, expected := range cases {
+		server := enterpriseServer(t, assessmentReply(t, `{"tokenProperties": {"valid": false, "invalidReason": "`+reason+`"}}`))
+		defer server.Close()
+		resp, err := newTestEnterprise(server.URL).CheckV3(context.Background(), gResponse, "")
+		if err != nil {
+			t.Fatalf("unexpected error occurred: %v", err)
+		}
+		if resp.Success || len(resp.Errors) != 1 || resp.Errors[0] != expected {
+			t.Errorf("%s should be mapped to %v but got %+v", reason, expected, resp.Errors)
+		}
+	}
+}
+
+func TestEnterpriseClientOptions(t *testing.T) {
+	server := enterpriseServer(t, assessmentReply(t, `{"riskAnalysis": {"score": 0.1}, "tokenProperties": {"valid": true, "action": "login", "hostname": "example.com"}}`))
+	defer server.Close()
+	enterprise := newTestEnterprise(server.URL,
+		recaptcha.WithAllowedHostnames("example.com"),
+		recaptcha.WithV3Policy(recaptcha.V3Policy{Threshold: 0.5}),
+	)
+	resp, err := enterprise.CheckV3(context.Background(), gResponse, "")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %v", err)
+	}
+	if resp.Success || len(resp.Errors) != 1 || !errors.Is(resp.Errors[0], recaptcha.ErrScoreTooLow) {
+		t.Errorf("the errors array should contain ErrScoreTooLow but it contains: %+v", resp.Errors)
+	}
+}
+
+func TestEnterpriseAPIError(t *testing.T) {
+	server := enterpriseServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+		w.Header().Set("Content-Type", jsonCT)
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error": {"code": 403, "message": "API key not valid", "status": "PERMISSION_DENIED"}}`))
+	})
+	defer server.Close()
+	_, err := newTestEnterprise(server.URL).Check(context.Background(), gResponse, "")
+	var statusErr *recaptcha.HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusForbidden {
+		t.Errorf("the error should be a 403 HTTPStatusError but it was %v", err)
+	}
+}
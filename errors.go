@@ -3,6 +3,8 @@ package recaptcha
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
 )
 
 // UserError is error type used for user generated errors
@@ -20,17 +22,85 @@ var (
 	ErrBadRequest = errors.New("the request is invalid or malformed")
 	// ErrInvalidInputResponse is produced when the user's response is invalid
 	ErrInvalidInputResponse = &UserError{message: "the response parameter is invalid or malformed"}
+	// ErrInvalidRemoteIP is produced when the remote IP is missing or invalid and the provider requires it (hCaptcha)
+	ErrInvalidRemoteIP = errors.New("the remoteip parameter is missing or invalid")
+	// ErrSitekeySecretMismatch is produced when the site key is not registered with the secret (hCaptcha)
+	ErrSitekeySecretMismatch = errors.New("the sitekey is not registered with the provided secret")
+	// ErrNotUsingDummyPasscode is produced when a test site key is used along with a real user response (hCaptcha)
+	ErrNotUsingDummyPasscode = errors.New("a test sitekey was used but the response is not the dummy passcode")
+	// ErrInvalidWidgetID is produced when the widget ID extracted from the secret is invalid or does not exist (Turnstile)
+	ErrInvalidWidgetID = errors.New("the widget ID extracted from the secret is invalid")
+	// ErrInvalidParsedSecret is produced when the secret extracted from the secret key is invalid (Turnstile)
+	ErrInvalidParsedSecret = errors.New("the secret extracted from the secret key is invalid")
+	// ErrInternalError is produced when the provider failed to verify the response, the verification can be retried
+	ErrInternalError = errors.New("the provider failed to verify the response due to an internal error")
+	// ErrServiceUnavailable is produced when the circuit breaker of a client is open and the API is not called
+	ErrServiceUnavailable = errors.New("the verification service is unavailable")
 	// ErrInvalidInputSecret is produced when the secret is invalid or malformed
 	ErrInvalidInputSecret = errors.New("the secret parameter is invalid or malformed")
 	// ErrTimeoutOrDuplicate is produced when user requests the verification of an already verified or expired captcha
 	ErrTimeoutOrDuplicate = &UserError{message: "the response is no longer valid: either is too old or has been used previously"}
+	// ErrHostnameMismatch is produced when the captcha was solved on a site that is not allowed by the client
+	ErrHostnameMismatch = &UserError{message: "the response was generated for a hostname that is not allowed"}
+	// ErrPackageNameMismatch is produced when the captcha was solved in an Android app that is not allowed by the client
+	ErrPackageNameMismatch = &UserError{message: "the response was generated for an Android package that is not allowed"}
+	// ErrChallengeExpired is produced when the captcha was loaded too long ago (or too far in the future) for the client to accept it
+	ErrChallengeExpired = &UserError{message: "the challenge timestamp is outside of the accepted time window"}
+	// ErrSiteMismatch is produced when the token was generated for another site key (reCAPTCHA Enterprise)
+	ErrSiteMismatch = &UserError{message: "the response was generated for another site key"}
+	// ErrBrowserError is produced when the token could not be generated because of a browser error, the user should retry (reCAPTCHA Enterprise)
+	ErrBrowserError = &UserError{message: "the response could not be generated due to a browser error"}
+	// ErrUnknownInvalidReason is produced when a token is invalid for an unspecified reason (reCAPTCHA Enterprise)
+	ErrUnknownInvalidReason = &UserError{message: "the response is invalid for an unknown reason"}
+	// ErrActionMismatch is produced when a Recaptcha v3 response does not have the action expected by a V3Policy
+	ErrActionMismatch = &UserError{message: "the response action does not match the expected one"}
+	// ErrScoreTooLow is produced when a Recaptcha v3 response score is below the threshold of a V3Policy
+	ErrScoreTooLow = &UserError{message: "the response score is lower than the required threshold"}
+	// ErrNoCassetteEntry is produced by ReplayTransport for the requests which were not recorded in its cassette
+	ErrNoCassetteEntry = errors.New("no matching exchange in the cassette")
 )
 
+// ActionMismatchError carries the actions involved in an ErrActionMismatch,
+// errors.Is(err, ErrActionMismatch) reports true for it
+type ActionMismatchError struct {
+	Expected string
+	Actual   string
+}
+
+// Error returns the error message
+func (err *ActionMismatchError) Error() string {
+	return fmt.Sprintf("%s: expected %q but got %q", ErrActionMismatch.Error(), err.Expected, err.Actual)
+}
+
+// Unwrap returns ErrActionMismatch
+func (err *ActionMismatchError) Unwrap() error {
+	return ErrActionMismatch
+}
+
+// ScoreTooLowError carries the values involved in an ErrScoreTooLow,
+// errors.Is(err, ErrScoreTooLow) reports true for it
+type ScoreTooLowError struct {
+	Action    string
+	Score     float64
+	Threshold float64
+}
+
+// Error returns the error message
+func (err *ScoreTooLowError) Error() string {
+	return fmt.Sprintf("%s: %.2f < %.2f (action %q)", ErrScoreTooLow.Error(), err.Score, err.Threshold, err.Action)
+}
+
+// Unwrap returns ErrScoreTooLow
+func (err *ScoreTooLowError) Unwrap() error {
+	return ErrScoreTooLow
+}
+
 // Errors allows to have a custom json unmarshalling implementation for a errors slice
 type Errors []error
 
 // UnmarshalJSON transforms a JSON array into an Errors type
 // "invalid-input-response", "invalid-input-response", "invalid-input-secret"," missing-input-secret" and "bad-request" are transformed into it's global errors counterpart
+// as well as the error codes specific to hCaptcha and Turnstile
 func (errs *Errors) UnmarshalJSON(b []byte) error {
 	var errorStrings []string
 	if err := json.Unmarshal(b, &errorStrings); err != nil {
@@ -45,7 +115,7 @@ func (errs *Errors) UnmarshalJSON(b []byte) error {
 			fallthrough
 		case "missing-input-response":
 			err = ErrInvalidInputResponse
-		case "timeout-or-duplicate":
+		case "timeout-or-duplicate", "expired-input-response", "already-seen-response", "invalid-or-already-seen-response":
 			err = ErrTimeoutOrDuplicate
 		case "invalid-input-secret":
 			fallthrough
@@ -53,6 +123,18 @@ func (errs *Errors) UnmarshalJSON(b []byte) error {
 			err = ErrInvalidInputSecret
 		case "bad-request":
 			err = ErrBadRequest
+		case "missing-remoteip", "invalid-remoteip":
+			err = ErrInvalidRemoteIP
+		case "sitekey-secret-mismatch":
+			err = ErrSitekeySecretMismatch
+		case "not-using-dummy-passcode":
+			err = ErrNotUsingDummyPasscode
+		case "invalid-widget-id":
+			err = ErrInvalidWidgetID
+		case "invalid-parsed-secret":
+			err = ErrInvalidParsedSecret
+		case "internal-error":
+			err = ErrInternalError
 		default:
 			err = errors.New(errString)
 		}
@@ -61,3 +143,89 @@ func (errs *Errors) UnmarshalJSON(b []byte) error {
 	*errs = result
 	return nil
 }
+
+// TransportError is returned when the verification endpoint could not be reached or the connection
+// failed while reading its response
+type TransportError struct {
+	Err error
+}
+
+// Error returns the error message
+func (err *TransportError) Error() string {
+	return err.Err.Error()
+}
+
+// Unwrap returns the underlying network error
+func (err *TransportError) Unwrap() error {
+	return err.Err
+}
+
+// HTTPStatusError is returned when the verification endpoint replies with a non-2xx status code
+type HTTPStatusError struct {
+	StatusCode int
+	// Body holds the beginning of the response body, it is truncated to a few hundred bytes
+	Body string
+	// RetryAfter is the delay requested by the Retry-After header, if any
+	RetryAfter time.Duration
+}
+
+// Error returns the error message
+func (err *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected response code %d", err.StatusCode)
+}
+
+// ContentTypeError is returned when the verification endpoint replies with something else than JSON,
+// for example an HTML error page
+type ContentTypeError struct {
+	ContentType string
+	// Body holds the beginning of the response body, it is truncated to a few hundred bytes
+	Body string
+}
+
+// Error returns the error message
+func (err *ContentTypeError) Error() string {
+	return fmt.Sprintf("Unexpected response Content-Type: %s", err.ContentType)
+}
+
+// DecodeError is returned when the response body of the verification endpoint is not valid
+type DecodeError struct {
+	Err error
+}
+
+// Error returns the error message
+func (err *DecodeError) Error() string {
+	return fmt.Sprintf("Error unmarshalling the response body: %s", err.Err.Error())
+}
+
+// Unwrap returns the underlying JSON error
+func (err *DecodeError) Unwrap() error {
+	return err.Err
+}
+
+// AuthError is returned when the access token of a reCAPTCHA Enterprise client could not be obtained, the API
+// is not called. It is never considered an unavailability of the API, even if the token endpoint is unreachable
+type AuthError struct {
+	Err error
+}
+
+// Error returns the error message
+func (err *AuthError) Error() string {
+	return fmt.Sprintf("unable to get an access token: %s", err.Err.Error())
+}
+
+// Unwrap returns the error of the token source
+func (err *AuthError) Unwrap() error {
+	return err.Err
+}
+
+// TransactionDataError is returned when the TransactionData of an event is invalid, the assessment is not created
+type TransactionDataError struct {
+	// Field is the path of the invalid field, e.g. "items[1].quantity"
+	Field  string
+	Reason string
+}
+
+// Error returns the error message
+func (err *TransactionDataError) Error() string {
+	return fmt.Sprintf("invalid transaction data: %s %s", err.Field, err.Reason)
+}
@@ -0,0 +1,66 @@
+package recaptcha
+
+import "strings"
+
+// checkExpectations flags successful responses that were produced for a site or app not allowed by the client
+func (c *Client) checkExpectations(response *Response) {
+	if !response.Success {
+		return
+	}
+
+	var err error
+	if response.ApkPackageName != "" {
+		if len(c.allowedPackageNames) != 0 {
+			if !containsString(c.allowedPackageNames, response.ApkPackageName) {
+				err = ErrPackageNameMismatch
+			}
+		} else if len(c.allowedHostnames) != 0 {
+			err = ErrHostnameMismatch
+		}
+	} else {
+		if len(c.allowedHostnames) != 0 {
+			if !matchHostname(c.allowedHostnames, response.Hostname) {
+				err = ErrHostnameMismatch
+			}
+		} else if len(c.allowedPackageNames) != 0 {
+			err = ErrPackageNameMismatch
+		}
+	}
+
+	if err != nil {
+		response.Success = false
+		response.Errors = append(response.Errors, err)
+	}
+}
+
+// matchHostname reports whether hostname matches any of the patterns, see WithAllowedHostnames
+func matchHostname(patterns []string, hostname string) bool {
+	hostname = normalizeHostname(hostname)
+	if hostname == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		pattern = normalizeHostname(pattern)
+		if strings.HasPrefix(pattern, "*.") {
+			if strings.HasSuffix(hostname, pattern[1:]) && len(hostname) > len(pattern)-1 {
+				return true
+			}
+		} else if hostname == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeHostname(hostname string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostname)), ".")
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
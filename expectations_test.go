@@ -0,0 +1,66 @@
+package recaptcha_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+func TestAllowedHostnames(t *testing.T) {
+	cases := []struct {
+		body    string
+		success bool
+	}{
+		{`{"success": true, "hostname": "example.com"}`, true},
+		{`{"success": true, "hostname": "www.example.com"}`, true},
+		{`{"success": true, "hostname": "a.b.example.com"}`, true},
+		{`{"success": true, "hostname": "EXAMPLE.com."}`, true},
+		{`{"success": true, "hostname": "evil.com"}`, false},
+		{`{"success": true, "hostname": "notexample.com"}`, false},
+		{`{"success": true, "hostname": ""}`, false},
+		{`{"success": true, "apk_package_name": "com.example.app"}`, false},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.body, func(t *testing.T) {
+			server := newTestServer(t, jsonReply(tc.body))
+			defer server.Close()
+			client := recaptcha.NewClient(
+				recaptcha.WithSecret(apiSecret),
+				recaptcha.WithVerifyURL(server.URL),
+				recaptcha.WithAllowedHostnames("example.com", "*.example.com"),
+			)
+			resp, err := client.Check(context.Background(), gResponse, "")
+			if err != nil {
+				t.Fatalf("unexpected error occurred: %v", err)
+			}
+			if resp.Success != tc.success {
+				t.Errorf("response.Success should be %t but it was %t", tc.success, resp.Success)
+			}
+			if !tc.success && (len(resp.Errors) != 1 || resp.Errors[0] != recaptcha.ErrHostnameMismatch) {
+				t.Errorf("the errors array should contain ErrHostnameMismatch but it contains: %+v", resp.Errors)
+			}
+		})
+	}
+}
+
+func TestAllowedPackageNames(t *testing.T) {
+	server := newTestServer(t, jsonReply(`{"success": true, "apk_package_name": "com.evil.app"}`))
+	defer server.Close()
+	client := recaptcha.NewClient(
+		recaptcha.WithSecret(apiSecret),
+		recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithAllowedPackageNames("com.example.app"),
+	)
+	resp := client.VerifyV3(gResponse, "")
+	if resp.Success {
+		t.Error("response.Success should be false but it was not")
+	}
+	if resp.ApkPackageName != "com.evil.app" {
+		t.Errorf("unexpected package name %s", resp.ApkPackageName)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0] != recaptcha.ErrPackageNameMismatch {
+		t.Errorf("the errors array should contain ErrPackageNameMismatch but it contains: %+v", resp.Errors)
+	}
+}
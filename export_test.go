@@ -0,0 +1,34 @@
+package recaptcha
+
+import "math/big"
+
+// The password leak tests play the role of the API, which needs the primitives of the protocol
+
+var (
+	Scrypt              = scrypt
+	LookupHashPrefix    = lookupHashPrefix
+	UserCredentialsHash = userCredentialsHash
+)
+
+type ECCipher = ecCipher
+
+func NewECCipher(key int64) *ECCipher {
+	return &ecCipher{key: big.NewInt(key)}
+}
+
+func (c *ecCipher) Encrypt(data []byte) []byte {
+	return c.encrypt(data)
+}
+
+func (c *ecCipher) Decrypt(point []byte) ([]byte, error) {
+	return c.decrypt(point)
+}
+
+// Reencrypt multiplies a compressed point by the key
+func (c *ecCipher) Reencrypt(point []byte) ([]byte, error) {
+	x, y, err := decompressPoint(point)
+	if err != nil {
+		return nil, err
+	}
+	return compressPoint(p256.ScalarMult(x, y, c.key.Bytes())), nil
+}
@@ -0,0 +1,223 @@
+package recaptcha
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TransactionData describes a payment transaction for the fraud prevention of reCAPTCHA Enterprise, read more at
+// https://cloud.google.com/recaptcha-enterprise/docs/reference/rest/v1/TransactionData
+type TransactionData struct {
+	// TransactionID (optional) is the unique identifier of the transaction
+	TransactionID string `json:"transactionId,omitempty"`
+	// PaymentMethod is the method of payment, e.g. "credit-card", "gift-card" or "PAYPAL"
+	PaymentMethod string `json:"paymentMethod,omitempty"`
+	// CardBin (optional) is the Bank Identification Number of the card, the first 6 to 8 digits
+	CardBin string `json:"cardBin,omitempty"`
+	// CardLastFour (optional) are the last four digits of the card
+	CardLastFour string `json:"cardLastFour,omitempty"`
+	// CurrencyCode is the ISO 4217 code of the currency of the values, e.g. "USD"
+	CurrencyCode string `json:"currencyCode,omitempty"`
+	// Value is the total value of the transaction, including the shipping
+	Value float64 `json:"value"`
+	// ShippingValue (optional) is the value of the shipping, included in Value
+	ShippingValue float64 `json:"shippingValue,omitempty"`
+
+	ShippingAddress *TransactionAddress `json:"shippingAddress,omitempty"`
+	BillingAddress  *TransactionAddress `json:"billingAddress,omitempty"`
+	User            *TransactionUser    `json:"user,omitempty"`
+	Merchants       []TransactionUser   `json:"merchants,omitempty"`
+	Items           []TransactionItem   `json:"items,omitempty"`
+	GatewayInfo     *GatewayInfo        `json:"gatewayInfo,omitempty"`
+}
+
+// TransactionAddress is a shipping or billing address
+type TransactionAddress struct {
+	Recipient string `json:"recipient,omitempty"`
+	// Address holds the street lines of the address
+	Address            []string `json:"address,omitempty"`
+	Locality           string   `json:"locality,omitempty"`
+	AdministrativeArea string   `json:"administrativeArea,omitempty"`
+	// RegionCode is the CLDR region code of the country, e.g. "US"
+	RegionCode string `json:"regionCode,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
+}
+
+// TransactionUser describes the buyer or a merchant of a transaction
+type TransactionUser struct {
+	AccountID string `json:"accountId,omitempty"`
+	// CreationMs is the creation time of the account in milliseconds since the Unix epoch
+	CreationMs    int64  `json:"creationMs,string,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified bool   `json:"emailVerified,omitempty"`
+	PhoneNumber   string `json:"phoneNumber,omitempty"`
+	PhoneVerified bool   `json:"phoneVerified,omitempty"`
+}
+
+// TransactionItem is an item bought in a transaction
+type TransactionItem struct {
+	Name string `json:"name,omitempty"`
+	// Value is the value of one unit of the item
+	Value             float64 `json:"value"`
+	Quantity          int64   `json:"quantity,string"`
+	MerchantAccountID string  `json:"merchantAccountId,omitempty"`
+}
+
+// GatewayInfo holds the answer of the payment gateway
+type GatewayInfo struct {
+	Name                string `json:"name,omitempty"`
+	GatewayResponseCode string `json:"gatewayResponseCode,omitempty"`
+	AVSResponseCode     string `json:"avsResponseCode,omitempty"`
+	CVVResponseCode     string `json:"cvvResponseCode,omitempty"`
+}
+
+// Validate checks the transaction before it is sent, the error is a *TransactionDataError
+func (d *TransactionData) Validate() error {
+	if d.PaymentMethod == "" {
+		return &TransactionDataError{Field: "paymentMethod", Reason: "is required"}
+	}
+	if len(d.CurrencyCode) != 3 || strings.ToUpper(d.CurrencyCode) != d.CurrencyCode || !isLetters(d.CurrencyCode) {
+		return &TransactionDataError{Field: "currencyCode", Reason: "should be an ISO 4217 code"}
+	}
+	if d.Value < 0 {
+		return &TransactionDataError{Field: "value", Reason: "cannot be negative"}
+	}
+	if d.ShippingValue < 0 || d.ShippingValue > d.Value {
+		return &TransactionDataError{Field: "shippingValue", Reason: "should be between 0 and value"}
+	}
+	if d.CardBin != "" && (len(d.CardBin) < 6 || len(d.CardBin) > 8 || !isDigits(d.CardBin)) {
+		return &TransactionDataError{Field: "cardBin", Reason: "should have 6 to 8 digits"}
+	}
+	if d.CardLastFour != "" && (len(d.CardLastFour) != 4 || !isDigits(d.CardLastFour)) {
+		return &TransactionDataError{Field: "cardLastFour", Reason: "should have 4 digits"}
+	}
+	for i, item := range d.Items {
+		field := fmt.Sprintf("items[%d]", i)
+		if item.Name == "" {
+			return &TransactionDataError{Field: field + ".name", Reason: "is required"}
+		}
+		if item.Value < 0 {
+			return &TransactionDataError{Field: field + ".value", Reason: "cannot be negative"}
+		}
+		if item.Quantity <= 0 {
+			return &TransactionDataError{Field: field + ".quantity", Reason: "should be positive"}
+		}
+	}
+	if err := validateAddress("shippingAddress", d.ShippingAddress); err != nil {
+		return err
+	}
+	return validateAddress("billingAddress", d.BillingAddress)
+}
+
+func validateAddress(field string, address *TransactionAddress) error {
+	if address != nil && address.RegionCode != "" && (len(address.RegionCode) != 2 || !isLetters(address.RegionCode)) {
+		return &TransactionDataError{Field: field + ".regionCode", Reason: "should be a two letters region code"}
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// FraudPreventionAssessment is the fraud risk analysis of a transaction, the risks go from 0 (lowest) to 1 (highest)
+type FraudPreventionAssessment struct {
+	// TransactionRisk is the probability of the transaction being fraudulent
+	TransactionRisk float64 `json:"transactionRisk"`
+	// StolenInstrumentVerdict assesses whether the payment instrument was stolen
+	StolenInstrumentVerdict RiskVerdict `json:"stolenInstrumentVerdict"`
+	// CardTestingVerdict assesses whether the transaction is part of a card testing attack
+	CardTestingVerdict RiskVerdict `json:"cardTestingVerdict"`
+}
+
+// RiskVerdict is the risk of a specific kind of fraud
+type RiskVerdict struct {
+	Risk float64 `json:"risk"`
+}
+
+// TransactionDecision is the outcome of a TransactionPolicy
+type TransactionDecision int
+
+const (
+	// TransactionAllow means the transaction can go on
+	TransactionAllow TransactionDecision = iota
+	// TransactionReview means the transaction should be reviewed or challenged (e.g. with 3-D Secure)
+	TransactionReview
+	// TransactionDeny means the transaction should be rejected
+	TransactionDeny
+)
+
+// String returns the name of the decision
+func (d TransactionDecision) String() string {
+	switch d {
+	case TransactionAllow:
+		return "allow"
+	case TransactionReview:
+		return "review"
+	case TransactionDeny:
+		return "deny"
+	}
+	return fmt.Sprintf("TransactionDecision(%d)", int(d))
+}
+
+// TransactionPolicy combines the score of the token with the fraud prevention risks to decide what to do with
+// a transaction. Risks above their Deny limit deny the transaction and those above their Review limit send it to
+// review, a zero limit is not applied
+type TransactionPolicy struct {
+	// MinScore is the minimum score of the token, lower scores deny the transaction
+	MinScore float64
+	// ReviewScore is the score under which the transaction is reviewed
+	ReviewScore float64
+
+	ReviewTransactionRisk float64
+	DenyTransactionRisk   float64
+
+	ReviewStolenInstrumentRisk float64
+	DenyStolenInstrumentRisk   float64
+
+	DenyCardTestingRisk float64
+}
+
+// Decide returns the decision of the policy for an assessment. Invalid tokens are denied, and only the
+// score is taken into account when the assessment has no FraudPrevention
+func (p TransactionPolicy) Decide(response EnterpriseResponse) TransactionDecision {
+	if !response.Success || response.Score < p.MinScore {
+		return TransactionDeny
+	}
+	decision := TransactionAllow
+	if response.Score < p.ReviewScore {
+		decision = TransactionReview
+	}
+
+	fraud := response.FraudPrevention
+	if fraud == nil {
+		return decision
+	}
+	if exceeds(fraud.TransactionRisk, p.DenyTransactionRisk) || exceeds(fraud.StolenInstrumentVerdict.Risk, p.DenyStolenInstrumentRisk) ||
+		exceeds(fraud.CardTestingVerdict.Risk, p.DenyCardTestingRisk) {
+		return TransactionDeny
+	}
+	if exceeds(fraud.TransactionRisk, p.ReviewTransactionRisk) || exceeds(fraud.StolenInstrumentVerdict.Risk, p.ReviewStolenInstrumentRisk) {
+		decision = TransactionReview
+	}
+	return decision
+}
+
+// exceeds reports whether risk is above a limit, a zero limit is never exceeded
+func exceeds(risk, limit float64) bool {
+	return limit > 0 && risk > limit
+}
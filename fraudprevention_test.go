@@ -0,0 +1,139 @@
+package recaptcha_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+func validTransaction() *recaptcha.TransactionData {
+	return &recaptcha.TransactionData{
+		TransactionID: "txn-1",
+		PaymentMethod: "credit-card",
+		CardBin:       "411111",
+		CardLastFour:  "1111",
+		CurrencyCode:  "USD",
+		Value:         39.98,
+		ShippingValue: 5,
+		BillingAddress: &recaptcha.TransactionAddress{
+			Recipient:  "Jane Doe",
+			Address:    []string{"1600 Amphitheatre Pkwy"},
+			Locality:   "Mountain View",
+			RegionCode: "US",
+			PostalCode: "94043",
+		},
+		User:        &recaptcha.TransactionUser{AccountID: "opaque-42", CreationMs: 1600000000000, EmailVerified: true},
+		Items:       []recaptcha.TransactionItem{{Name: "Socks", Value: 17.49, Quantity: 2}},
+		GatewayInfo: &recaptcha.GatewayInfo{Name: "stripe", CVVResponseCode: "M"},
+	}
+}
+
+func TestTransactionDataValidate(t *testing.T) {
+	if err := validTransaction().Validate(); err != nil {
+		t.Fatalf("the transaction should be valid but got %v", err)
+	}
+
+	tests := []struct {
+		field  string
+		mutate func(d *recaptcha.TransactionData)
+	}{
+		{"paymentMethod", func(d *recaptcha.TransactionData) { d.PaymentMethod = "" }},
+		{"currencyCode", func(d *recaptcha.TransactionData) { d.CurrencyCode = "usd" }},
+		{"value", func(d *recaptcha.TransactionData) { d.Value, d.ShippingValue = -1, 0 }},
+		{"shippingValue", func(d *recaptcha.TransactionData) { d.ShippingValue = 100 }},
+		{"cardBin", func(d *recaptcha.TransactionData) { d.CardBin = "4111" }},
+		{"cardLastFour", func(d *recaptcha.TransactionData) { d.CardLastFour = "11a1" }},
+		{"items[0].quantity", func(d *recaptcha.TransactionData) { d.Items[0].Quantity = 0 }},
+		{"billingAddress.regionCode", func(d *recaptcha.TransactionData) { d.BillingAddress.RegionCode = "USA" }},
+	}
+	for _, tc := range tests {
+		data := validTransaction()
+		tc.mutate(data)
+		var dataErr *recaptcha.TransactionDataError
+		if err := data.Validate(); !errors.As(err, &dataErr) || dataErr.Field != tc.field {
+			t.Errorf("the field %s should be invalid but got %v", tc.field, err)
+		}
+	}
+}
+
+func TestEnterpriseFraudPrevention(t *testing.T) {
+	server := enterpriseServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+		event, _ := body["event"].(map[string]interface{})
+		transaction, _ := event["transactionData"].(map[string]interface{})
+		items, _ := transaction["items"].([]interface{})
+		user, _ := transaction["user"].(map[string]interface{})
+		if transaction["cardBin"] != "411111" || len(items) != 1 || user["creationMs"] != "1600000000000" {
+			t.Errorf("unexpected transaction data %v", transaction)
+		}
+		jsonReply(`{
+			"tokenProperties": {"valid": true},
+			"riskAnalysis": {"score": 0.9},
+			"fraudPreventionAssessment": {
+				"transactionRisk": 0.65,
+				"stolenInstrumentVerdict": {"risk": 0.2},
+				"cardTestingVerdict": {"risk": 0.1}
+			}
+		}`)(w, r)
+	})
+	defer server.Close()
+	enterprise := newTestEnterprise(server.URL)
+	resp, err := enterprise.Assess(context.Background(), recaptcha.Event{Token: gResponse, TransactionData: validTransaction()})
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %v", err)
+	}
+	if resp.FraudPrevention == nil || resp.FraudPrevention.TransactionRisk != 0.65 || resp.FraudPrevention.StolenInstrumentVerdict.Risk != 0.2 {
+		t.Fatalf("unexpected fraud prevention assessment %+v", resp.FraudPrevention)
+	}
+	policy := recaptcha.TransactionPolicy{MinScore: 0.3, ReviewTransactionRisk: 0.5, DenyTransactionRisk: 0.8}
+	if decision := policy.Decide(resp); decision != recaptcha.TransactionReview {
+		t.Errorf("the transaction should be reviewed but the decision was %s", decision)
+	}
+
+	invalid := validTransaction()
+	invalid.CurrencyCode = ""
+	if _, err := enterprise.Assess(context.Background(), recaptcha.Event{Token: gResponse, TransactionData: invalid}); err == nil {
+		t.Error("an invalid transaction should not be sent")
+	}
+}
+
+func TestTransactionPolicyDecide(t *testing.T) {
+	policy := recaptcha.TransactionPolicy{
+		MinScore:                   0.3,
+		ReviewScore:                0.5,
+		ReviewTransactionRisk:      0.5,
+		DenyTransactionRisk:        0.8,
+		ReviewStolenInstrumentRisk: 0.4,
+		DenyStolenInstrumentRisk:   0.9,
+		DenyCardTestingRisk:        0.7,
+	}
+	assessment := func(success bool, score float64, fraud *recaptcha.FraudPreventionAssessment) recaptcha.EnterpriseResponse {
+		var resp recaptcha.EnterpriseResponse
+		resp.Success, resp.Score, resp.FraudPrevention = success, score, fraud
+		return resp
+	}
+
+	tests := []struct {
+		name     string
+		response recaptcha.EnterpriseResponse
+		expected recaptcha.TransactionDecision
+	}{
+		{"invalid token", assessment(false, 0.9, nil), recaptcha.TransactionDeny},
+		{"low score", assessment(true, 0.1, nil), recaptcha.TransactionDeny},
+		{"doubtful score", assessment(true, 0.4, nil), recaptcha.TransactionReview},
+		{"no fraud assessment", assessment(true, 0.9, nil), recaptcha.TransactionAllow},
+		{"low risk", assessment(true, 0.9, &recaptcha.FraudPreventionAssessment{TransactionRisk: 0.1}), recaptcha.TransactionAllow},
+		{"stolen instrument", assessment(true, 0.9, &recaptcha.FraudPreventionAssessment{
+			StolenInstrumentVerdict: recaptcha.RiskVerdict{Risk: 0.5}}), recaptcha.TransactionReview},
+		{"card testing", assessment(true, 0.9, &recaptcha.FraudPreventionAssessment{
+			CardTestingVerdict: recaptcha.RiskVerdict{Risk: 0.75}}), recaptcha.TransactionDeny},
+		{"high risk", assessment(true, 0.9, &recaptcha.FraudPreventionAssessment{TransactionRisk: 0.85}), recaptcha.TransactionDeny},
+	}
+	for _, tc := range tests {
+		if decision := policy.Decide(tc.response); decision != tc.expected {
+			t.Errorf("%s: the decision should be %s but it was %s", tc.name, tc.expected, decision)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package recaptcha
+
+import "time"
+
+const defaultClockSkew = 30 * time.Second
+
+// checkFreshness flags successful responses whose challenge was loaded outside of the accepted time window
+func (c *Client) checkFreshness(response *Response) {
+	if !response.Success || c.maxChallengeAge <= 0 {
+		return
+	}
+
+	if !c.isFresh(response.ChallengeTimeStamp) {
+		response.Success = false
+		response.Errors = append(response.Errors, ErrChallengeExpired)
+	}
+}
+
+func (c *Client) isFresh(ts string) bool {
+	challengeTime, err := ParseTimeStamp(ts)
+	if err != nil {
+		// without a valid timestamp the freshness of the challenge can't be proven
+		return false
+	}
+
+	now := c.now()
+	if challengeTime.After(now.Add(c.clockSkew)) {
+		return false
+	}
+	return now.Sub(challengeTime) <= c.maxChallengeAge
+}
@@ -0,0 +1,48 @@
+package recaptcha_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+func TestMaxChallengeAge(t *testing.T) {
+	now := time.Date(2020, 8, 16, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		ts      string
+		success bool
+	}{
+		{"2020-08-16T11:59:00Z", true},
+		{"2020-08-16T13:58:00+0200", true},
+		{"2020-08-16T11:57:59Z", false},
+		{"2020-08-16T12:00:20Z", true},
+		{"2020-08-16T12:01:00Z", false},
+		{"", false},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.ts, func(t *testing.T) {
+			server := newTestServer(t, jsonReply(fmt.Sprintf(`{"success": true, "challenge_ts": %q}`, tc.ts)))
+			defer server.Close()
+			client := recaptcha.NewClient(
+				recaptcha.WithSecret(apiSecret),
+				recaptcha.WithVerifyURL(server.URL),
+				recaptcha.WithMaxChallengeAge(2*time.Minute),
+				recaptcha.WithClock(func() time.Time { return now }),
+			)
+			resp := client.Verify(gResponse, "")
+			if resp.Success != tc.success {
+				t.Errorf("response.Success should be %t but it was %t", tc.success, resp.Success)
+			}
+			if !tc.success {
+				var userErr *recaptcha.UserError
+				if len(resp.Errors) != 1 || resp.Errors[0] != recaptcha.ErrChallengeExpired || !errors.As(resp.Errors[0], &userErr) {
+					t.Errorf("the errors array should contain ErrChallengeExpired but it contains: %+v", resp.Errors)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package recaptcha
+
+import (
+	"context"
+	"net/http"
+)
+
+// HCaptchaVerifyURL is the hCaptcha siteverify endpoint
+const HCaptchaVerifyURL = "https://api.hcaptcha.com/siteverify"
+
+// HCaptchaFormField is the form field where the hCaptcha widget stores the user response
+const HCaptchaFormField = "h-captcha-response"
+
+// HCaptchaResponse represents the hCaptcha verification response
+type HCaptchaResponse struct {
+	Response
+	// Whether the response will be credited (optional)
+	Credit bool `json:"credit"`
+	// the risk score of the request (0.0 - 1.0), unlike Recaptcha v3 a higher score means a riskier request (Enterprise only)
+	Score float64 `json:"score"`
+	// Reasons for the score (Enterprise only)
+	ScoreReason []string `json:"score_reason"`
+}
+
+// HCaptcha verifies hCaptcha user responses, it accepts the same options as NewClient
+// (WithSecret, WithSiteKey, WithHTTPClient, WithRetry...). The hCaptcha specific error codes are
+// reported using the errors of this package, e.g. "already-seen-response" is ErrTimeoutOrDuplicate
+type HCaptcha struct {
+	client *Client
+}
+
+var _ Verifier = (*HCaptcha)(nil)
+
+// NewHCaptcha creates a hCaptcha verifier configured with the given options
+func NewHCaptcha(opts ...Option) *HCaptcha {
+	return &HCaptcha{client: newClient(HCaptchaVerifyURL, HCaptchaFormField, opts)}
+}
+
+// Check verifies an hCaptcha user response, see Client.Check
+func (h *HCaptcha) Check(ctx context.Context, clientResponse, remoteIP string) (response Response, err error) {
+	err = h.client.verify(ctx, h.client.secret, clientResponse, remoteIP, &response)
+	return response, err
+}
+
+// CheckEnterprise verifies an hCaptcha user response and returns the hCaptcha specific fields
+func (h *HCaptcha) CheckEnterprise(ctx context.Context, clientResponse, remoteIP string) (response HCaptchaResponse, err error) {
+	err = h.client.verify(ctx, h.client.secret, clientResponse, remoteIP, &response)
+	return response, err
+}
+
+// FormField returns HCaptchaFormField
+func (h *HCaptcha) FormField() string {
+	return h.client.formField
+}
+
+// RemoteIP returns the user's IP of a request, see Client.RemoteIP
+func (h *HCaptcha) RemoteIP(r *http.Request) string {
+	return h.client.RemoteIP(r)
+}
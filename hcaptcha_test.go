@@ -0,0 +1,87 @@
+package recaptcha_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+const hSiteKey = "10000000-ffff-ffff-ffff-000000000001"
+
+func TestHCaptchaCheck(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if sitekey := r.Form.Get("sitekey"); sitekey != hSiteKey {
+			t.Errorf("the sitekey should be %s but %s was found", hSiteKey, sitekey)
+		}
+		if secret := r.Form.Get("secret"); secret != apiSecret {
+			t.Errorf("the secret should be %s but %s was found", apiSecret, secret)
+		}
+		jsonReply(`{"success": true, "hostname": "example.com", "credit": true, "score": 0.2, "score_reason": ["safe"]}`)(w, r)
+	})
+	defer server.Close()
+	hcaptcha := recaptcha.NewHCaptcha(
+		recaptcha.WithSecret(apiSecret),
+		recaptcha.WithSiteKey(hSiteKey),
+		recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithAllowedHostnames("example.com"),
+	)
+
+	resp, err := hcaptcha.CheckEnterprise(context.Background(), gResponse, "")
+	if err != nil || !resp.Success {
+		t.Fatalf("the response should be successful but got %+v, %v", resp, err)
+	}
+	if !resp.Credit || resp.Score != 0.2 || len(resp.ScoreReason) != 1 || resp.ScoreReason[0] != "safe" {
+		t.Errorf("the enterprise fields were not decoded: %+v", resp)
+	}
+	if hcaptcha.FormField() != "h-captcha-response" {
+		t.Errorf("unexpected form field %s", hcaptcha.FormField())
+	}
+}
+
+func TestHCaptchaErrorCodes(t *testing.T) {
+	cases := map[string]error{
+		"already-seen-response":            recaptcha.ErrTimeoutOrDuplicate,
+		"invalid-or-already-seen-response": recaptcha.ErrTimeoutOrDuplicate,
+		"expired-input-response":           recaptcha.ErrTimeoutOrDuplicate,
+		"missing-remoteip":                 recaptcha.ErrInvalidRemoteIP,
+		"invalid-remoteip":                 recaptcha.ErrInvalidRemoteIP,
+		"sitekey-secret-mismatch":          recaptcha.ErrSitekeySecretMismatch,
+		"not-using-dummy-passcode":         recaptcha.ErrNotUsingDummyPasscode,
+	}
+	for code, expected := range cases {
+		server := newTestServer(t, jsonReply(`{"success": false, "error-codes": ["`+code+`"]}`))
+		defer server.Close()
+		hcaptcha := recaptcha.NewHCaptcha(recaptcha.WithSecret(apiSecret), recaptcha.WithVerifyURL(server.URL))
+		resp, err := hcaptcha.Check(context.Background(), gResponse, "")
+		if err != nil {
+			t.Fatalf("unexpected error occurred: %v", err)
+		}
+		if len(resp.Errors) != 1 || resp.Errors[0] != expected {
+			t.Errorf("%s should be mapped to %v but got %+v", code, expected, resp.Errors)
+		}
+	}
+}
+
+func TestMiddlewareHCaptcha(t *testing.T) {
+	server := tokenServer(t)
+	defer server.Close()
+	hcaptcha := recaptcha.NewHCaptcha(recaptcha.WithSecret(apiSecret), recaptcha.WithVerifyURL(server.URL))
+	handler := recaptcha.Middleware(hcaptcha, recaptcha.MiddlewareOptions{V3: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{"h-captcha-response": {gResponse}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "192.0.2.1:1234"
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("the request should be allowed but the status was %d", recorder.Code)
+	}
+}
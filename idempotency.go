@@ -0,0 +1,103 @@
+package recaptcha
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// WithIdempotencyWindow makes the client remember successful verdicts for window, so verifying again the same
+// user response from the same IP with the same parameters (e.g. a double submitted form) returns the first verdict
+// with Cached set to true instead of failing with ErrTimeoutOrDuplicate. Concurrent verifications of the same
+// response wait for the first one
+func WithIdempotencyWindow(window time.Duration) Option {
+	return func(c *Client) {
+		c.idempotency = &idempotencyCache{
+			window:  window,
+			entries: make(map[string]*idempotencyEntry),
+		}
+	}
+}
+
+type idempotencyCache struct {
+	mu        sync.Mutex
+	window    time.Duration
+	entries   map[string]*idempotencyEntry
+	lastSweep time.Time
+}
+
+type idempotencyEntry struct {
+	// done is closed once the verification finishes, value and expiry can't be read before
+	done   chan struct{}
+	value  interface{}
+	expiry time.Time
+}
+
+// do returns the cached verdict for the user response if there is one, otherwise it runs verify and caches its result.
+// The fingerprint holds the other parameters of the request, a verdict is only reused for the same fingerprint
+func (cache *idempotencyCache) do(ctx context.Context, now func() time.Time, clientResponse, remoteIP, fingerprint string, result verdict, verify func() error) error {
+	key := fmt.Sprintf("%T|%s|%s|%s", result, tokenHash(clientResponse), remoteIP, tokenHash(fingerprint))
+	for {
+		cache.mu.Lock()
+		cache.sweep(now())
+		entry, found := cache.entries[key]
+		if found && entry.value != nil && !now().Before(entry.expiry) {
+			found = false
+		}
+		if !found {
+			entry = &idempotencyEntry{done: make(chan struct{})}
+			cache.entries[key] = entry
+			cache.mu.Unlock()
+			return cache.fill(key, entry, now, result, verify)
+		}
+		cache.mu.Unlock()
+
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return &TransportError{Err: ctx.Err()}
+		}
+		if entry.value != nil {
+			reflect.ValueOf(result).Elem().Set(reflect.ValueOf(entry.value))
+			result.response().Cached = true
+			return nil
+		}
+		// the verification we waited for failed, so it's our turn to try
+	}
+}
+
+// fill runs verify for a new entry, the entry is removed unless the verification succeeds and the waiting callers
+// are released even if verify panics
+func (cache *idempotencyCache) fill(key string, entry *idempotencyEntry, now func() time.Time, result verdict, verify func() error) (err error) {
+	completed := false
+	defer func() {
+		cache.mu.Lock()
+		if completed && err == nil && result.response().Success && !result.response().Degraded {
+			entry.value = reflect.ValueOf(result).Elem().Interface()
+			entry.expiry = now().Add(cache.window)
+		} else {
+			delete(cache.entries, key)
+		}
+		cache.mu.Unlock()
+		close(entry.done)
+	}()
+
+	err = verify()
+	completed = true
+	return err
+}
+
+// sweep removes the expired entries, it must be called with the lock held
+func (cache *idempotencyCache) sweep(now time.Time) {
+	if now.Sub(cache.lastSweep) < cache.window {
+		return
+	}
+	for key, entry := range cache.entries {
+		if entry.value != nil && !now.Before(entry.expiry) {
+			delete(cache.entries, key)
+		}
+	}
+	cache.lastSweep = now
+}
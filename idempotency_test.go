@@ -0,0 +1,140 @@
+package recaptcha_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+func TestIdempotencyWindow(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		jsonReply(`{"success": true, "score": 0.9, "action": "submit"}`)(w, r)
+	})
+	defer server.Close()
+	client := recaptcha.NewClient(
+		recaptcha.WithSecret(apiSecret),
+		recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithReplayStore(recaptcha.NewMemoryReplayStore(), 0),
+		recaptcha.WithIdempotencyWindow(time.Minute),
+	)
+
+	var wg sync.WaitGroup
+	responses := make([]recaptcha.ResponseV3, 3)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = client.VerifyV3(gResponse, clientIP)
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	cached := 0
+	for _, resp := range responses {
+		if !resp.Success || resp.Score != 0.9 || resp.Action != "submit" {
+			t.Errorf("every response should be the successful verdict but got: %+v", resp)
+		}
+		if resp.Cached {
+			cached++
+		}
+	}
+	if cached != 2 {
+		t.Errorf("two responses should have been served from cache but %d were", cached)
+	}
+
+	if resp := client.VerifyV3(gResponse, clientIP); !resp.Success || !resp.Cached {
+		t.Errorf("the verdict should have been served from cache but got: %+v", resp)
+	}
+	// a different IP or response type does not share the verdict
+	if resp := client.VerifyV3(gResponse, "10.0.0.1"); resp.Success || resp.Cached {
+		t.Errorf("the response should have been rejected by the replay guard but got: %+v", resp)
+	}
+	if resp := client.Verify(gResponse, clientIP); resp.Success || resp.Cached {
+		t.Errorf("the response should have been rejected by the replay guard but got: %+v", resp)
+	}
+	if calls := atomic.LoadInt32(&calls); calls != 1 {
+		t.Errorf("the API should have been called once but it was called %d times", calls)
+	}
+}
+
+func TestIdempotencyWindowEnterpriseEvents(t *testing.T) {
+	var calls int32
+	server := enterpriseServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+		atomic.AddInt32(&calls, 1)
+		jsonReply(`{"riskAnalysis": {"score": 0.9}, "tokenProperties": {"valid": true, "action": "login"}}`)(w, r)
+	})
+	defer server.Close()
+	enterprise := newTestEnterprise(server.URL, recaptcha.WithIdempotencyWindow(time.Minute))
+
+	event := recaptcha.Event{Token: gResponse, ExpectedAction: "login", UserIPAddress: clientIP}
+	for i := 0; i < 2; i++ {
+		resp, err := enterprise.Assess(context.Background(), event)
+		if err != nil || !resp.Success || resp.Cached != (i == 1) {
+			t.Errorf("unexpected assessment %+v, %v", resp, err)
+		}
+	}
+	// other events with the same token do not share the verdict
+	event.ExpectedAction = "signup"
+	resp, err := enterprise.Assess(context.Background(), event)
+	if err != nil || resp.Cached || len(resp.Errors) != 1 || !errors.Is(resp.Errors[0], recaptcha.ErrActionMismatch) {
+		t.Errorf("the errors array should contain ErrActionMismatch but got %+v, %v", resp, err)
+	}
+	event.ExpectedAction = "login"
+	event.TransactionData = &recaptcha.TransactionData{PaymentMethod: "credit-card", CurrencyCode: "USD", Value: 10}
+	if resp, err := enterprise.Assess(context.Background(), event); err != nil || !resp.Success || resp.Cached {
+		t.Errorf("the assessment should not be served from cache but got %+v, %v", resp, err)
+	}
+	if calls := atomic.LoadInt32(&calls); calls != 3 {
+		t.Errorf("the API should have been called 3 times but it was called %d times", calls)
+	}
+}
+
+// panickingTransport panics on its first request and sends the next ones to the default transport
+type panickingTransport struct {
+	calls int32
+}
+
+func (t *panickingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if atomic.AddInt32(&t.calls, 1) == 1 {
+		panic("transport failure")
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestIdempotencyWindowPanic(t *testing.T) {
+	server := newTestServer(t, jsonReply(`{"success": true}`))
+	defer server.Close()
+	client := recaptcha.NewClient(
+		recaptcha.WithSecret(apiSecret),
+		recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithHTTPClient(&http.Client{Transport: &panickingTransport{}}),
+		recaptcha.WithIdempotencyWindow(time.Minute),
+	)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("the verification should have panicked")
+			}
+		}()
+		client.Check(context.Background(), gResponse, clientIP)
+	}()
+
+	// the next verification does not wait for the one which panicked
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if resp, err := client.Check(ctx, gResponse, clientIP); err != nil || !resp.Success || resp.Cached {
+		t.Errorf("the response should have been verified again but got %+v, %v", resp, err)
+	}
+}
@@ -0,0 +1,163 @@
+package recaptcha
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// DefaultFormField is the form field where the reCAPTCHA widget stores the user response
+const DefaultFormField = "g-recaptcha-response"
+
+// defaultMaxBodySize is how much of a body the middleware reads looking for the user response
+const defaultMaxBodySize = 1 << 20
+
+// MiddlewareOptions configures Middleware
+type MiddlewareOptions struct {
+	// FormField is the form field holding the user response, the one of the verifier (see Verifier.FormField) if empty
+	FormField string
+	// Header (optional) is a request header holding the user response, it takes precedence over the body
+	Header string
+	// JSONField (optional) is a top-level field of JSON request bodies holding the user response
+	JSONField string
+	// MaxBodySize limits how much of a form or JSON body is read looking for the user response (1MB by default),
+	// the response of larger bodies is not found
+	MaxBodySize int64
+	// V3 makes the middleware verify Recaptcha v3 responses, it is ignored by the verifiers without
+	// Recaptcha v3 compatible scores
+	V3 bool
+	// RemoteIP (optional) returns the user's IP sent to the API, by default it is found out by the verifier
+	// (see Client.RemoteIP) or it is the host of r.RemoteAddr
+	RemoteIP func(r *http.Request) string
+	// OnReject (optional) handles the requests which fail the verification, err is only set when
+	// the verification itself failed. By default it replies 403 Forbidden, or 503 Service Unavailable if err is set
+	OnReject func(w http.ResponseWriter, r *http.Request, response ResponseV3, err error)
+}
+
+type contextKey struct{}
+
+// ResponseFromContext returns the response stored by Middleware in a request context.
+// Recaptcha v2 responses are stored as a ResponseV3 without score nor action
+func ResponseFromContext(ctx context.Context) (ResponseV3, bool) {
+	response, ok := ctx.Value(contextKey{}).(ResponseV3)
+	return response, ok
+}
+
+// Middleware returns an HTTP middleware which only lets through the requests carrying a valid user response,
+// the response is stored in the request context (see ResponseFromContext). The request body remains readable
+// for the next handlers
+func Middleware(verifier Verifier, opts MiddlewareOptions) func(http.Handler) http.Handler {
+	if opts.FormField == "" {
+		opts.FormField = verifier.FormField()
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = defaultMaxBodySize
+	}
+	if opts.RemoteIP == nil {
+		if resolver, ok := verifier.(remoteIPResolver); ok {
+			opts.RemoteIP = resolver.RemoteIP
+		} else {
+			opts.RemoteIP = func(r *http.Request) string { return ClientIP(r, nil) }
+		}
+	}
+	scorer, _ := verifier.(scoreVerifier)
+	if opts.OnReject == nil {
+		opts.OnReject = defaultReject
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			clientResponse := opts.extractResponse(r)
+			remoteIP := opts.RemoteIP(r)
+
+			var response ResponseV3
+			var err error
+			if opts.V3 && scorer != nil {
+				response, err = scorer.CheckV3(r.Context(), clientResponse, remoteIP)
+			} else {
+				response.Response, err = verifier.Check(r.Context(), clientResponse, remoteIP)
+			}
+			if err != nil || !response.Success {
+				opts.OnReject(w, r, response, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, response)))
+		})
+	}
+}
+
+func (opts *MiddlewareOptions) extractResponse(r *http.Request) string {
+	if opts.Header != "" {
+		if value := r.Header.Get(opts.Header); value != "" {
+			return value
+		}
+	}
+	if opts.JSONField != "" && strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		return extractJSONField(r, opts.JSONField, opts.MaxBodySize)
+	}
+	return extractFormField(r, opts.FormField, opts.MaxBodySize)
+}
+
+// peekBody reads the body of the request, which is replaced by an equivalent one so the next handlers
+// can still read it. It reports false when the body could not be read or is larger than maxBodySize
+func peekBody(r *http.Request, maxBodySize int64) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, false
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+	return data, err == nil && int64(len(data)) <= maxBodySize
+}
+
+// extractFormField reads a field of an urlencoded or multipart form body, without consuming the body
+func extractFormField(r *http.Request, field string, maxBodySize int64) string {
+	contentType := r.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "application/x-www-form-urlencoded") && !strings.HasPrefix(contentType, "multipart/form-data") {
+		return ""
+	}
+	data, ok := peekBody(r, maxBodySize)
+	if !ok {
+		return ""
+	}
+
+	// the form is parsed from a copy of the request so r.Form and r.PostForm stay unset for the next handlers
+	form := r.Clone(r.Context())
+	form.Body = ioutil.NopCloser(bytes.NewReader(data))
+	form.ContentLength = int64(len(data))
+	value := form.PostFormValue(field)
+	if form.MultipartForm != nil {
+		form.MultipartForm.RemoveAll()
+	}
+	return value
+}
+
+// extractJSONField reads a string field of a JSON body, without consuming the body
+func extractJSONField(r *http.Request, field string, maxBodySize int64) string {
+	data, ok := peekBody(r, maxBodySize)
+	if !ok {
+		return ""
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return ""
+	}
+	var value string
+	json.Unmarshal(fields[field], &value)
+	return value
+}
+
+func defaultReject(w http.ResponseWriter, r *http.Request, response ResponseV3, err error) {
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	http.Error(w, "invalid captcha", http.StatusForbidden)
+}
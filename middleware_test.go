@@ -0,0 +1,144 @@
+package recaptcha_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+// tokenServer accepts gResponse and rejects any other user response, it checks the remote IP too
+func tokenServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("response") == gResponse && r.Form.Get("remoteip") == "192.0.2.1" {
+			jsonReply(`{"success": true, "score": 0.9, "action": "submit"}`)(w, r)
+			return
+		}
+		jsonReply(`{"success": false, "error-codes": ["invalid-input-response"]}`)(w, r)
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	server := tokenServer(t)
+	defer server.Close()
+	client := recaptcha.NewClient(recaptcha.WithSecret(apiSecret), recaptcha.WithVerifyURL(server.URL))
+	var gotBody string
+	var gotResponse recaptcha.ResponseV3
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		gotBody = string(body)
+		gotResponse, _ = recaptcha.ResponseFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := recaptcha.Middleware(client, recaptcha.MiddlewareOptions{
+		Header:    "X-Captcha",
+		JSONField: "captcha",
+		V3:        true,
+	})(next)
+
+	cases := []struct {
+		name   string
+		req    func() *http.Request
+		status int
+	}{
+		{"form", func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{"g-recaptcha-response": {gResponse}}.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			return req
+		}, http.StatusNoContent},
+		{"header", func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set("X-Captcha", gResponse)
+			return req
+		}, http.StatusNoContent},
+		{"json", func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"user": "bob", "captcha": "`+gResponse+`"}`))
+			req.Header.Set("Content-Type", "application/json")
+			return req
+		}, http.StatusNoContent},
+		{"invalid", func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set("X-Captcha", "forged")
+			return req
+		}, http.StatusForbidden},
+		{"missing", func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/", nil)
+		}, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			gotBody, gotResponse = "", recaptcha.ResponseV3{}
+			req := tc.req()
+			req.RemoteAddr = "192.0.2.1:1234"
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			if recorder.Code != tc.status {
+				t.Errorf("the status should be %d but it was %d", tc.status, recorder.Code)
+			}
+			if tc.status == http.StatusNoContent && (!gotResponse.Success || gotResponse.Score != 0.9) {
+				t.Errorf("the response should be stored in the context but got %+v", gotResponse)
+			}
+		})
+	}
+
+	// the bodies are still readable by the next handler
+	var multipartBody bytes.Buffer
+	writer := multipart.NewWriter(&multipartBody)
+	writer.WriteField("user", "bob")
+	writer.WriteField("g-recaptcha-response", gResponse)
+	writer.Close()
+	bodies := []struct {
+		contentType string
+		body        string
+	}{
+		{"application/json", `{"captcha": "` + gResponse + `"}`},
+		{"application/x-www-form-urlencoded", url.Values{"user": {"bob"}, "g-recaptcha-response": {gResponse}}.Encode()},
+		{writer.FormDataContentType(), multipartBody.String()},
+	}
+	for _, b := range bodies {
+		gotBody, gotResponse = "", recaptcha.ResponseV3{}
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(b.body))
+		req.Header.Set("Content-Type", b.contentType)
+		req.RemoteAddr = "192.0.2.1:1234"
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if !gotResponse.Success || gotBody != b.body {
+			t.Errorf("the %s body should be preserved but the next handler read %q", b.contentType, gotBody)
+		}
+	}
+}
+
+func TestMiddlewareOnReject(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	defer server.Close()
+	client := recaptcha.NewClient(recaptcha.WithSecret(apiSecret), recaptcha.WithVerifyURL(server.URL))
+	var rejectErr error
+	handler := recaptcha.Middleware(client, recaptcha.MiddlewareOptions{
+		Header: "X-Captcha",
+		OnReject: func(w http.ResponseWriter, r *http.Request, response recaptcha.ResponseV3, err error) {
+			rejectErr = err
+			w.WriteHeader(http.StatusTeapot)
+		},
+	})(http.NotFoundHandler())
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-Captcha", gResponse)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusTeapot {
+		t.Errorf("the rejection handler should have been called but the status was %d", recorder.Code)
+	}
+	if !recaptcha.IsServiceUnavailable(rejectErr) {
+		t.Errorf("the rejection handler should get the verification error but got %v", rejectErr)
+	}
+}
@@ -0,0 +1,298 @@
+package recaptcha
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CloudPlatformScope is the OAuth2 scope required by the reCAPTCHA Enterprise API
+const CloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// Token is an OAuth2 access token
+type Token struct {
+	AccessToken string
+	TokenType   string
+	// Expiry is the zero time for tokens which never expire
+	Expiry time.Time
+}
+
+// expiryDelta is how long before their expiry tokens are considered expired, to make up for the latency
+const expiryDelta = 10 * time.Second
+
+func (t *Token) expired(now time.Time) bool {
+	return !t.Expiry.IsZero() && !now.Add(expiryDelta).Before(t.Expiry)
+}
+
+// TokenSource provides the OAuth2 access tokens used to call Google APIs, implementations must be thread-safe
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// WithTokenSource makes the Enterprise client authenticate with OAuth2 access tokens instead of an API key
+func WithTokenSource(source TokenSource) EnterpriseOption {
+	return func(e *Enterprise) {
+		e.tokenSource = source
+	}
+}
+
+type staticTokenSource struct {
+	token *Token
+}
+
+func (s staticTokenSource) Token(ctx context.Context) (*Token, error) {
+	return s.token, nil
+}
+
+// StaticTokenSource returns a TokenSource which always provides the same access token
+func StaticTokenSource(accessToken string) TokenSource {
+	return staticTokenSource{token: &Token{AccessToken: accessToken, TokenType: "Bearer"}}
+}
+
+// ReuseTokenSource caches the tokens of source. Tokens are refreshed in the background once they are
+// closer than refreshBefore to their expiry, so callers only wait for a new token when the cached one expired
+func ReuseTokenSource(source TokenSource, refreshBefore time.Duration) TokenSource {
+	return &cachedTokenSource{source: source, refreshBefore: refreshBefore, now: time.Now}
+}
+
+type cachedTokenSource struct {
+	source        TokenSource
+	refreshBefore time.Duration
+	now           func() time.Time
+
+	mu         sync.Mutex
+	token      *Token
+	refreshing bool
+	// fetchMu serializes the synchronous fetches
+	fetchMu sync.Mutex
+}
+
+func (s *cachedTokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	token := s.token
+	if token != nil && !token.expired(s.now()) {
+		if !token.Expiry.IsZero() && !s.refreshing && s.now().Add(s.refreshBefore).After(token.Expiry) {
+			s.refreshing = true
+			go s.refresh()
+		}
+		s.mu.Unlock()
+		return token, nil
+	}
+	s.mu.Unlock()
+
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+	// another caller may have fetched a token while we waited
+	s.mu.Lock()
+	token = s.token
+	s.mu.Unlock()
+	if token != nil && !token.expired(s.now()) {
+		return token, nil
+	}
+
+	token, err := s.source.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.token = token
+	s.mu.Unlock()
+	return token, nil
+}
+
+func (s *cachedTokenSource) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	token, err := s.source.Token(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshing = false
+	// on failure the current token is kept, the next call will try again
+	if err == nil {
+		s.token = token
+	}
+}
+
+// serviceAccountKey is the JSON key file of a Google service account
+type serviceAccountKey struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+}
+
+type serviceAccountTokenSource struct {
+	key        serviceAccountKey
+	privateKey *rsa.PrivateKey
+	scopes     []string
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewServiceAccountTokenSource creates a TokenSource from the JSON key of a Google service account. It signs
+// a JWT assertion (RS256) with the key and exchanges it for an access token at the token_uri of the key.
+// Tokens are cached and refreshed ahead of their expiry. httpClient is optional, scopes default to CloudPlatformScope
+func NewServiceAccountTokenSource(jsonKey []byte, httpClient *http.Client, scopes ...string) (TokenSource, error) {
+	var key serviceAccountKey
+	if err := json.Unmarshal(jsonKey, &key); err != nil {
+		return nil, fmt.Errorf("invalid service account key: %w", err)
+	}
+	if key.Type != "service_account" || key.ClientEmail == "" || key.TokenURI == "" {
+		return nil, errors.New("invalid service account key: it should be a service_account key with client_email and token_uri")
+	}
+	privateKey, err := parseRSAPrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service account key: %w", err)
+	}
+	if len(scopes) == 0 {
+		scopes = []string{CloudPlatformScope}
+	}
+	if httpClient == nil {
+		httpClient = HTTPClient
+	}
+
+	source := &serviceAccountTokenSource{key: key, privateKey: privateKey, scopes: scopes, httpClient: httpClient, now: time.Now}
+	return ReuseTokenSource(source, 5*time.Minute), nil
+}
+
+func parseRSAPrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("the private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+func (s *serviceAccountTokenSource) Token(ctx context.Context) (*Token, error) {
+	assertion, err := s.assertion()
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.key.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return fetchToken(s.httpClient, req, s.now)
+}
+
+// assertion returns a signed JWT asserting the identity of the service account
+func (s *serviceAccountTokenSource) assertion() (string, error) {
+	now := s.now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": s.key.PrivateKeyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   s.key.ClientEmail,
+		"scope": strings.Join(s.scopes, " "),
+		"aud":   s.key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// metadataTokenPath is the path of the default service account token in the metadata server
+const metadataTokenPath = "/computeMetadata/v1/instance/service-accounts/default/token"
+
+type metadataTokenSource struct {
+	host       string
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewMetadataTokenSource creates a TokenSource which gets the tokens of the default service account from the
+// metadata server available in GCE, GKE (with Workload Identity), Cloud Run... The server host can be
+// overridden with the GCE_METADATA_HOST environment variable. httpClient is optional
+func NewMetadataTokenSource(httpClient *http.Client) TokenSource {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	if httpClient == nil {
+		httpClient = HTTPClient
+	}
+	return ReuseTokenSource(&metadataTokenSource{host: host, httpClient: httpClient, now: time.Now}, 5*time.Minute)
+}
+
+func (s *metadataTokenSource) Token(ctx context.Context) (*Token, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+s.host+metadataTokenPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	return fetchToken(s.httpClient, req, s.now)
+}
+
+// fetchToken sends a token request and decodes the token of the response
+func fetchToken(httpClient *http.Client, req *http.Request, now func() time.Time) (*Token, error) {
+	response, err := checkHTTPResponse(httpClient.Do(req))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, &TransportError{Err: fmt.Errorf("unable to read response body %w", err)}
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := unmarshalJSONBody(body, &result); err != nil {
+		return nil, err
+	}
+	if result.AccessToken == "" {
+		return nil, &DecodeError{Err: errors.New("the response does not contain an access token")}
+	}
+	token := &Token{AccessToken: result.AccessToken, TokenType: result.TokenType}
+	if result.ExpiresIn > 0 {
+		token.Expiry = now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
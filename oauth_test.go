@@ -0,0 +1,206 @@
+package recaptcha_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+// oauthServer is a stand-in of the Google OAuth2 token endpoint, at /token, which checks the JWT assertions
+func oauthServer(t *testing.T, publicKey *rsa.PublicKey, calls *int32) *httptest.Server {
+	t.Helper()
+	var tokenURI string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		r.ParseForm()
+		if grant := r.Form.Get("grant_type"); grant != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			t.Errorf("unexpected grant type %s", grant)
+		}
+		parts := strings.Split(r.Form.Get("assertion"), ".")
+		if len(parts) != 3 {
+			t.Fatalf("the assertion should be a JWT")
+		}
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+			t.Errorf("the assertion signature is invalid: %v", err)
+		}
+		var header, claims map[string]interface{}
+		headerJSON, _ := base64.RawURLEncoding.DecodeString(parts[0])
+		claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		json.Unmarshal(headerJSON, &header)
+		json.Unmarshal(claimsJSON, &claims)
+		if header["alg"] != "RS256" || header["kid"] != "key-1" {
+			t.Errorf("unexpected JWT header %v", header)
+		}
+		if claims["iss"] != "recaptcha@my-project.iam.gserviceaccount.com" || claims["aud"] != tokenURI ||
+			claims["scope"] != recaptcha.CloudPlatformScope {
+			t.Errorf("unexpected JWT claims %v", claims)
+		}
+		jsonReply(`{"access_token": "ya29.token", "token_type": "Bearer", "expires_in": 3600}`)(w, r)
+	})
+	tokenURI = server.URL + "/token"
+	return server
+}
+
+func serviceAccountKey(t *testing.T, tokenURI string) ([]byte, *rsa.PublicKey) {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate a key: %v", err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+	key, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "my-project",
+		"private_key_id": "key-1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "recaptcha@my-project.iam.gserviceaccount.com",
+		"token_uri":      tokenURI,
+	})
+	return key, &privateKey.PublicKey
+}
+
+func TestServiceAccountTokenSource(t *testing.T) {
+	var calls int32
+	var publicKey rsa.PublicKey
+	oauth := oauthServer(t, &publicKey, &calls)
+	defer oauth.Close()
+	tokenURI := oauth.URL + "/token"
+	key, generated := serviceAccountKey(t, tokenURI)
+	publicKey = *generated
+
+	source, err := recaptcha.NewServiceAccountTokenSource(key, nil)
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		token, err := source.Token(context.Background())
+		if err != nil || token.AccessToken != "ya29.token" || time.Until(token.Expiry) < 59*time.Minute {
+			t.Errorf("unexpected token %+v, %v", token, err)
+		}
+	}
+	if calls := atomic.LoadInt32(&calls); calls != 1 {
+		t.Errorf("the token should be cached but it was fetched %d times", calls)
+	}
+
+	// the token is sent to the Enterprise API
+	server := enterpriseServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer ya29.token" {
+			t.Errorf("unexpected Authorization header %q", auth)
+		}
+		jsonReply(`{"tokenProperties": {"valid": true}}`)(w, r)
+	})
+	defer server.Close()
+	enterprise := recaptcha.NewEnterprise(enterpriseProject, enterpriseSiteKey,
+		recaptcha.WithEnterpriseURL(server.URL), recaptcha.WithTokenSource(source))
+	if resp, err := enterprise.Check(context.Background(), gResponse, ""); err != nil || !resp.Success {
+		t.Errorf("the assessment should be successful but got %+v, %v", resp, err)
+	}
+}
+
+func TestServiceAccountTokenSourceInvalidKey(t *testing.T) {
+	for _, key := range []string{`not json`, `{"type": "authorized_user"}`,
+		`{"type": "service_account", "client_email": "a@b", "token_uri": "http://x", "private_key": "nope"}`} {
+		if _, err := recaptcha.NewServiceAccountTokenSource([]byte(key), nil); err == nil {
+			t.Errorf("an error was expected for the key %s", key)
+		}
+	}
+}
+
+type countingTokenSource struct {
+	calls int32
+	ttl   time.Duration
+}
+
+func (s *countingTokenSource) Token(ctx context.Context) (*recaptcha.Token, error) {
+	n := atomic.AddInt32(&s.calls, 1)
+	return &recaptcha.Token{AccessToken: string(rune('a' + n - 1)), Expiry: time.Now().Add(s.ttl)}, nil
+}
+
+func TestReuseTokenSourceProactiveRefresh(t *testing.T) {
+	source := &countingTokenSource{ttl: time.Minute}
+	cached := recaptcha.ReuseTokenSource(source, 2*time.Minute)
+
+	first, _ := cached.Token(context.Background())
+	second, _ := cached.Token(context.Background())
+	if first.AccessToken != "a" || second.AccessToken != "a" {
+		t.Errorf("the cached token should be returned while it is refreshed but got %s and %s", first.AccessToken, second.AccessToken)
+	}
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&source.calls) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if calls := atomic.LoadInt32(&source.calls); calls < 2 {
+		t.Errorf("the token should have been refreshed in the background")
+	}
+}
+
+func TestMetadataTokenSource(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" || r.URL.Path != "/computeMetadata/v1/instance/service-accounts/default/token" {
+			t.Errorf("unexpected metadata request %s %v", r.URL.Path, r.Header)
+		}
+		jsonReply(`{"access_token": "ya29.metadata", "token_type": "Bearer", "expires_in": 3599}`)(w, r)
+	})
+	defer server.Close()
+	os.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+	defer os.Unsetenv("GCE_METADATA_HOST")
+	token, err := recaptcha.NewMetadataTokenSource(nil).Token(context.Background())
+	if err != nil || token.AccessToken != "ya29.metadata" {
+		t.Errorf("unexpected token %+v, %v", token, err)
+	}
+}
+
+func TestTokenSourceFailureFailOpen(t *testing.T) {
+	// the metadata server answers without an access token
+	metadata := newTestServer(t, jsonReply(`{"token_type": "Bearer"}`))
+	defer metadata.Close()
+	os.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(metadata.URL, "http://"))
+	defer os.Unsetenv("GCE_METADATA_HOST")
+
+	var calls int32
+	server := enterpriseServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+		atomic.AddInt32(&calls, 1)
+		jsonReply(`{"tokenProperties": {"valid": true}}`)(w, r)
+	})
+	defer server.Close()
+	enterprise := recaptcha.NewEnterprise(enterpriseProject, enterpriseSiteKey,
+		recaptcha.WithEnterpriseURL(server.URL),
+		recaptcha.WithTokenSource(recaptcha.NewMetadataTokenSource(nil)),
+		recaptcha.WithClientOptions(
+			recaptcha.WithCircuitBreaker(recaptcha.BreakerSettings{FailureThreshold: 1}),
+			recaptcha.WithDegradationMode(recaptcha.FailOpen),
+			recaptcha.WithRetry(recaptcha.RetryPolicy{MaxAttempts: 3}),
+		))
+
+	for i := 0; i < 2; i++ {
+		resp, err := enterprise.Check(context.Background(), gResponse, "")
+		var authErr *recaptcha.AuthError
+		if !errors.As(err, &authErr) || resp.Success || resp.Degraded {
+			t.Errorf("an AuthError was expected but got %+v, %v", resp, err)
+		}
+		if recaptcha.IsServiceUnavailable(err) {
+			t.Errorf("a token failure should not be a service unavailability: %v", err)
+		}
+	}
+	if calls := atomic.LoadInt32(&calls); calls != 0 {
+		t.Errorf("the API should not be called without a token but it was called %d times", calls)
+	}
+}
@@ -0,0 +1,242 @@
+package recaptcha
+
+import (
+	"bytes"
+	"context"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/big"
+	"net/url"
+	"strings"
+)
+
+// Scrypt parameters of the user credentials hash
+const (
+	credentialsHashN      = 4096
+	credentialsHashR      = 8
+	credentialsHashP      = 1
+	credentialsHashLength = 32
+)
+
+// lookupHashPrefixBits is the length of the username hash prefix sent to the API
+const lookupHashPrefixBits = 26
+
+// usernameHashSalt is appended to the canonical username to salt the hash of the lookup prefix,
+// it is the salt used by Google's password leak detection clients
+var usernameHashSalt = []byte{
+	0xc4, 0x94, 0xa3, 0x95, 0xf8, 0xc0, 0xe2, 0x3e, 0xa9, 0x23, 0x04, 0x78, 0x70, 0x2c, 0x72, 0x18,
+	0x56, 0x54, 0x99, 0xb3, 0xe9, 0x21, 0x18, 0x6c, 0x21, 0x1a, 0x01, 0x22, 0x3c, 0x45, 0x4a, 0xfa,
+}
+
+// credentialsHashSalt is appended to the canonical username to salt the credentials hash,
+// it is the salt used by Google's password leak detection clients
+var credentialsHashSalt = []byte{
+	0x30, 0x76, 0x2a, 0xd2, 0x3f, 0x7b, 0xa1, 0x9b, 0xf8, 0xe3, 0x42, 0xfc, 0xa1, 0xa7, 0x8d, 0x06,
+	0xe6, 0x6b, 0xe4, 0xdb, 0xb8, 0x4f, 0x81, 0x53, 0xc5, 0x03, 0xc8, 0xdb, 0xbd, 0xde, 0xa5, 0x20,
+}
+
+// passwordLeakVerification is the wire representation of a private password leak verification
+type passwordLeakVerification struct {
+	LookupHashPrefix               []byte   `json:"lookupHashPrefix,omitempty"`
+	EncryptedUserCredentialsHash   []byte   `json:"encryptedUserCredentialsHash,omitempty"`
+	ReencryptedUserCredentialsHash []byte   `json:"reencryptedUserCredentialsHash,omitempty"`
+	EncryptedLeakMatchPrefixes     [][]byte `json:"encryptedLeakMatchPrefixes,omitempty"`
+}
+
+// PasswordLeakCheck reports whether the credentials are known to have leaked, using the private password leak
+// verification of reCAPTCHA Enterprise. Neither the credentials nor their hash leave the process: the API only
+// receives a 26 bits prefix of the username hash and the credentials hash encrypted with a one-time key, and it
+// answers with the hash re-encrypted with its own key along with the encrypted leaks matching the prefix
+func (e *Enterprise) PasswordLeakCheck(ctx context.Context, username, password string) (bool, error) {
+	verification, cipher, err := newPasswordLeakVerification(rand.Reader, username, password)
+	if err != nil {
+		return false, err
+	}
+
+	var result struct {
+		PrivatePasswordLeakVerification passwordLeakVerification `json:"privatePasswordLeakVerification"`
+	}
+	_, err = e.post(ctx, "projects/"+url.PathEscape(e.project)+"/assessments",
+		assessment{PrivatePasswordLeakVerification: verification}, &result)
+	if err != nil {
+		return false, err
+	}
+	return cipher.matchLeaks(result.PrivatePasswordLeakVerification)
+}
+
+// newPasswordLeakVerification computes the request of a password leak verification, the returned cipher
+// holds the one-time key needed to read the response
+func newPasswordLeakVerification(random io.Reader, username, password string) (*passwordLeakVerification, *ecCipher, error) {
+	canonical := CanonicalizeUsername(username)
+	if canonical == "" || password == "" {
+		return nil, nil, errors.New("the username and the password are required")
+	}
+	credentialsHash, err := userCredentialsHash(canonical, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	cipher, err := newECCipher(random)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &passwordLeakVerification{
+		LookupHashPrefix:             lookupHashPrefix(canonical),
+		EncryptedUserCredentialsHash: cipher.encrypt(credentialsHash),
+	}, cipher, nil
+}
+
+// CanonicalizeUsername returns the form of a username used to look up leaks: lowercase, without the
+// domain of email addresses and without dots
+func CanonicalizeUsername(username string) string {
+	canonical := strings.ToLower(username)
+	if at := strings.LastIndex(canonical, "@"); at >= 0 {
+		canonical = canonical[:at]
+	}
+	return strings.ReplaceAll(canonical, ".", "")
+}
+
+// lookupHashPrefix returns the first 26 bits of the salted SHA-256 hash of the canonical username
+func lookupHashPrefix(canonicalUsername string) []byte {
+	hash := sha256.Sum256(append([]byte(canonicalUsername), usernameHashSalt...))
+	prefix := hash[:(lookupHashPrefixBits+7)/8]
+	prefix[len(prefix)-1] &^= 0xff >> (lookupHashPrefixBits % 8)
+	return prefix
+}
+
+// userCredentialsHash returns the scrypt hash of the canonical username and the password
+func userCredentialsHash(canonicalUsername, password string) ([]byte, error) {
+	salt := append([]byte(canonicalUsername), credentialsHashSalt...)
+	return scrypt([]byte(canonicalUsername+password), salt, credentialsHashN, credentialsHashR, credentialsHashP, credentialsHashLength)
+}
+
+// ecCipher is a commutative cipher over P-256: a value is hashed to a point of the curve which is multiplied
+// by the key, so encrypting with a key and then with another one gives the same point in any order
+type ecCipher struct {
+	key *big.Int
+}
+
+var p256 = elliptic.P256()
+
+func newECCipher(random io.Reader) (*ecCipher, error) {
+	n := p256.Params().N
+	for {
+		key, err := rand.Int(random, n)
+		if err != nil {
+			return nil, err
+		}
+		if key.Sign() > 0 {
+			return &ecCipher{key: key}, nil
+		}
+	}
+}
+
+// encrypt hashes data to the curve and returns the compressed point multiplied by the key
+func (c *ecCipher) encrypt(data []byte) []byte {
+	x, y := hashToCurve(data)
+	return compressPoint(p256.ScalarMult(x, y, c.key.Bytes()))
+}
+
+// decrypt removes the encryption of the key from a compressed point
+func (c *ecCipher) decrypt(point []byte) ([]byte, error) {
+	x, y, err := decompressPoint(point)
+	if err != nil {
+		return nil, err
+	}
+	inverse := new(big.Int).ModInverse(c.key, p256.Params().N)
+	return compressPoint(p256.ScalarMult(x, y, inverse.Bytes())), nil
+}
+
+// matchLeaks decrypts the re-encrypted credentials hash, which leaves it encrypted only with the key of the
+// API, and looks for the prefix of its SHA-256 hash among the leaks
+func (c *ecCipher) matchLeaks(result passwordLeakVerification) (bool, error) {
+	decrypted, err := c.decrypt(result.ReencryptedUserCredentialsHash)
+	if err != nil {
+		return false, &DecodeError{Err: err}
+	}
+	hash := sha256.Sum256(decrypted)
+	for _, prefix := range result.EncryptedLeakMatchPrefixes {
+		if len(prefix) > 0 && bytes.HasPrefix(hash[:], prefix) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// hashToCurve maps data to a point of P-256 like the commutative cipher of Google's Private Join and Compute
+// library, which the leak detection clients use: the x coordinate is drawn from a random oracle and drawn again
+// from its own bytes until a point with that coordinate exists. The point with an even y is used
+func hashToCurve(data []byte) (x, y *big.Int) {
+	params := p256.Params()
+	x = randomOracle(data, params.P)
+	for {
+		if y = curveY(x, 0); y != nil {
+			return x, y
+		}
+		x = randomOracle(x.Bytes(), params.P)
+	}
+}
+
+// randomOracle hashes data to an integer lower than max: the SHA-256 hashes of data prefixed by the counters
+// 1, 2... are concatenated until they exceed the length of max by 256 bits, and the result is reduced modulo max
+func randomOracle(data []byte, max *big.Int) *big.Int {
+	const hashBits = 256
+	outputBits := max.BitLen() + hashBits
+	iterations := (outputBits + hashBits - 1) / hashBits
+	output := new(big.Int)
+	for i := 1; i <= iterations; i++ {
+		hash := sha256.Sum256(append(big.NewInt(int64(i)).Bytes(), data...))
+		output.Lsh(output, hashBits)
+		output.Add(output, new(big.Int).SetBytes(hash[:]))
+	}
+	output.Rsh(output, uint(iterations*hashBits-outputBits))
+	return output.Mod(output, max)
+}
+
+// curveY returns the y coordinate of the point with the given x and parity, or nil if there is none
+func curveY(x *big.Int, parity uint) *big.Int {
+	params := p256.Params()
+	// y² = x³ - 3x + b
+	y2 := new(big.Int).Exp(x, big.NewInt(3), params.P)
+	threeX := new(big.Int).Lsh(x, 1)
+	threeX.Add(threeX, x)
+	y2.Sub(y2, threeX)
+	y2.Add(y2, params.B)
+	y2.Mod(y2, params.P)
+
+	y := new(big.Int).ModSqrt(y2, params.P)
+	if y == nil {
+		return nil
+	}
+	if y.Bit(0) != parity {
+		y.Sub(params.P, y)
+	}
+	return y
+}
+
+// compressPoint encodes a point in the SEC 1 compressed form
+func compressPoint(x, y *big.Int) []byte {
+	point := make([]byte, 33)
+	point[0] = 2 + byte(y.Bit(0))
+	xBytes := x.Bytes()
+	copy(point[33-len(xBytes):], xBytes)
+	return point
+}
+
+// decompressPoint decodes a point in the SEC 1 compressed form
+func decompressPoint(point []byte) (x, y *big.Int, err error) {
+	if len(point) != 33 || (point[0] != 2 && point[0] != 3) {
+		return nil, nil, errors.New("invalid compressed point")
+	}
+	x = new(big.Int).SetBytes(point[1:])
+	if x.Cmp(p256.Params().P) >= 0 {
+		return nil, nil, errors.New("invalid compressed point")
+	}
+	y = curveY(x, uint(point[0]-2))
+	if y == nil {
+		return nil, nil, errors.New("the point is not on the curve")
+	}
+	return x, y, nil
+}
@@ -0,0 +1,159 @@
+package recaptcha_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+func TestScrypt(t *testing.T) {
+	// RFC 7914 section 12
+	tests := []struct {
+		password, salt string
+		N, r, p        int
+		expected       string
+	}{
+		{"", "", 16, 1, 1, "77d6576238657b203b19ca42c18a0497f16b4844e3074ae8dfdffa3fede21442fcd0069ded0948f8326a753a0fc81f17e8d3e0fb2e0d3628cf35e20c38d18906"},
+		{"password", "NaCl", 1024, 8, 16, "fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b3731622eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640"},
+	}
+	for _, tc := range tests {
+		key, err := recaptcha.Scrypt([]byte(tc.password), []byte(tc.salt), tc.N, tc.r, tc.p, 64)
+		if err != nil {
+			t.Fatalf("unexpected error occurred: %v", err)
+		}
+		if hex.EncodeToString(key) != tc.expected {
+			t.Errorf("scrypt(%q, %q) should be %s but it was %x", tc.password, tc.salt, tc.expected, key)
+		}
+	}
+	if _, err := recaptcha.Scrypt(nil, nil, 1000, 8, 1, 32); err == nil {
+		t.Error("N should be a power of two")
+	}
+}
+
+func TestPasswordLeakHashes(t *testing.T) {
+	for username, expected := range map[string]string{
+		"jonsnow":              "jonsnow",
+		"Jon.Snow@Example.com": "jonsnow",
+		"jon@snow@example.com": "jon@snow",
+	} {
+		if canonical := recaptcha.CanonicalizeUsername(username); canonical != expected {
+			t.Errorf("the canonical form of %s should be %s but it was %q", username, expected, canonical)
+		}
+	}
+	// the username hash of the tests of Chromium's leak detection, 3d70d37b... truncated to 26 bits
+	if prefix := hex.EncodeToString(recaptcha.LookupHashPrefix("jonsnow")); prefix != "3d70d340" {
+		t.Errorf("unexpected lookup hash prefix %s", prefix)
+	}
+	hash, err := recaptcha.UserCredentialsHash("user", "password123")
+	if err != nil || hex.EncodeToString(hash) != "997ef676074ccdb4c8aeda1f723df9674c5b34cc2fea6b4d767bf283855573fd" {
+		t.Errorf("unexpected credentials hash %x, %v", hash, err)
+	}
+	if point := recaptcha.NewECCipher(1).Encrypt(hash); point[0] != 2 {
+		t.Errorf("the credentials hash should be mapped to a point with an even y but got %x", point)
+	}
+}
+
+func TestECCipherCommutative(t *testing.T) {
+	client, server := recaptcha.NewECCipher(123456789), recaptcha.NewECCipher(987654321)
+	data := []byte("credentials hash")
+
+	clientFirst, err := server.Reencrypt(client.Encrypt(data))
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %v", err)
+	}
+	serverFirst, err := client.Reencrypt(server.Encrypt(data))
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %v", err)
+	}
+	if !bytes.Equal(clientFirst, serverFirst) {
+		t.Error("the encryption order should not matter")
+	}
+	decrypted, err := client.Decrypt(clientFirst)
+	if err != nil || !bytes.Equal(decrypted, server.Encrypt(data)) {
+		t.Errorf("decrypting should remove the client encryption but got %x, %v", decrypted, err)
+	}
+	if _, err := client.Decrypt([]byte{2, 1, 2, 3}); err == nil {
+		t.Error("an invalid point should not be decrypted")
+	}
+}
+
+// leakServer is a stand-in of the password leak verification of the API which knows the given leaks
+func leakServer(t *testing.T, leaks map[string]string) *httptest.Server {
+	t.Helper()
+	serverCipher := recaptcha.NewECCipher(424242)
+	return enterpriseServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+		var request struct {
+			PrivatePasswordLeakVerification struct {
+				LookupHashPrefix             []byte `json:"lookupHashPrefix"`
+				EncryptedUserCredentialsHash []byte `json:"encryptedUserCredentialsHash"`
+			} `json:"privatePasswordLeakVerification"`
+		}
+		raw, _ := json.Marshal(body)
+		json.Unmarshal(raw, &request)
+		verification := request.PrivatePasswordLeakVerification
+		if len(verification.LookupHashPrefix) != 4 || verification.LookupHashPrefix[3]&0x3f != 0 {
+			t.Errorf("the lookup hash prefix should have 26 bits but it was %x", verification.LookupHashPrefix)
+		}
+
+		reencrypted, err := serverCipher.Reencrypt(verification.EncryptedUserCredentialsHash)
+		if err != nil {
+			t.Fatalf("the encrypted credentials hash is invalid: %v", err)
+		}
+		var prefixes [][]byte
+		for username, password := range leaks {
+			if !bytes.Equal(recaptcha.LookupHashPrefix(username), verification.LookupHashPrefix) {
+				continue
+			}
+			hash, _ := recaptcha.UserCredentialsHash(username, password)
+			encrypted := sha256.Sum256(serverCipher.Encrypt(hash))
+			prefixes = append(prefixes, encrypted[:4])
+		}
+		reply, _ := json.Marshal(map[string]interface{}{
+			"name": "projects/my-project/assessments/leak",
+			"privatePasswordLeakVerification": map[string]interface{}{
+				"lookupHashPrefix":               verification.LookupHashPrefix,
+				"encryptedUserCredentialsHash":   verification.EncryptedUserCredentialsHash,
+				"reencryptedUserCredentialsHash": reencrypted,
+				"encryptedLeakMatchPrefixes":     prefixes,
+			},
+		})
+		jsonReply(string(reply))(w, r)
+	})
+}
+
+func TestPasswordLeakCheck(t *testing.T) {
+	server := leakServer(t, map[string]string{"alice": "hunter2", "bob": "correct horse"})
+	defer server.Close()
+	enterprise := newTestEnterprise(server.URL)
+
+	tests := []struct {
+		username, password string
+		leaked             bool
+	}{
+		{"Alice@example.com", "hunter2", true},
+		{"a.lice", "hunter2", true},
+		{"alice", "hunter3", false},
+		{"bob", "correct horse", true},
+		{"carol", "hunter2", false},
+	}
+	for _, tc := range tests {
+		leaked, err := enterprise.PasswordLeakCheck(context.Background(), tc.username, tc.password)
+		if err != nil {
+			t.Fatalf("unexpected error occurred: %v", err)
+		}
+		if leaked != tc.leaked {
+			t.Errorf("the leak of %s/%s should be %v", tc.username, tc.password, tc.leaked)
+		}
+	}
+
+	if _, err := enterprise.PasswordLeakCheck(context.Background(), "", "hunter2"); err == nil {
+		t.Error("an empty username should be rejected")
+	}
+}
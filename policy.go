@@ -0,0 +1,44 @@
+package recaptcha
+
+// V3Policy describes which Recaptcha v3 responses are acceptable for an application.
+// Read more about scores and actions at https://developers.google.com/recaptcha/docs/v3#interpreting_the_score
+type V3Policy struct {
+	// Action is the expected action, any action is accepted when it is empty
+	Action string
+	// ActionThresholds holds the minimum score required for specific actions
+	ActionThresholds map[string]float64
+	// Threshold is the minimum score required for the actions not present in ActionThresholds
+	Threshold float64
+}
+
+// Evaluate reports whether the response is acceptable under the policy.
+// Unsuccessful responses are never allowed, the returned error is only set when it is the policy
+// which rejects the response and it is either an *ActionMismatchError or a *ScoreTooLowError
+func (p V3Policy) Evaluate(response ResponseV3) (allowed bool, err error) {
+	if !response.Success {
+		return false, nil
+	}
+	if p.Action != "" && response.Action != p.Action {
+		return false, &ActionMismatchError{Expected: p.Action, Actual: response.Action}
+	}
+
+	threshold, ok := p.ActionThresholds[response.Action]
+	if !ok {
+		threshold = p.Threshold
+	}
+	if response.Score < threshold {
+		return false, &ScoreTooLowError{Action: response.Action, Score: response.Score, Threshold: threshold}
+	}
+	return true, nil
+}
+
+// ApplyPolicy evaluates the policy against the response, when the policy rejects it
+// the error is appended to Errors and Success is set to false. It returns whether the response is allowed
+func (r *ResponseV3) ApplyPolicy(policy V3Policy) bool {
+	allowed, err := policy.Evaluate(*r)
+	if err != nil {
+		r.Success = false
+		r.Errors = append(r.Errors, err)
+	}
+	return allowed
+}
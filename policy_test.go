@@ -0,0 +1,73 @@
+package recaptcha_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+func TestV3PolicyEvaluate(t *testing.T) {
+	policy := recaptcha.V3Policy{
+		ActionThresholds: map[string]float64{"login": 0.7},
+		Threshold:        0.5,
+	}
+	response := func(success bool, action string, score float64) recaptcha.ResponseV3 {
+		return recaptcha.ResponseV3{Response: recaptcha.Response{Success: success}, Action: action, Score: score}
+	}
+
+	if allowed, err := policy.Evaluate(response(true, "login", 0.7)); !allowed || err != nil {
+		t.Errorf("the response should be allowed but got %t, %v", allowed, err)
+	}
+	if allowed, err := policy.Evaluate(response(true, "search", 0.5)); !allowed || err != nil {
+		t.Errorf("the response should be allowed but got %t, %v", allowed, err)
+	}
+	if allowed, err := policy.Evaluate(response(false, "login", 0.9)); allowed || err != nil {
+		t.Errorf("unsuccessful responses should be rejected without a policy error but got %t, %v", allowed, err)
+	}
+
+	allowed, err := policy.Evaluate(response(true, "login", 0.6))
+	var scoreErr *recaptcha.ScoreTooLowError
+	if allowed || !errors.As(err, &scoreErr) {
+		t.Fatalf("the response should be rejected with a ScoreTooLowError but got %t, %v", allowed, err)
+	}
+	if scoreErr.Score != 0.6 || scoreErr.Threshold != 0.7 || scoreErr.Action != "login" {
+		t.Errorf("unexpected error values: %+v", scoreErr)
+	}
+	var userErr *recaptcha.UserError
+	if !errors.Is(err, recaptcha.ErrScoreTooLow) || !errors.As(err, &userErr) {
+		t.Errorf("the error should be a user error matching ErrScoreTooLow but it was %v", err)
+	}
+
+	policy.Action = "login"
+	allowed, err = policy.Evaluate(response(true, "signup", 0.9))
+	var actionErr *recaptcha.ActionMismatchError
+	if allowed || !errors.As(err, &actionErr) {
+		t.Fatalf("the response should be rejected with an ActionMismatchError but got %t, %v", allowed, err)
+	}
+	if actionErr.Expected != "login" || actionErr.Actual != "signup" {
+		t.Errorf("unexpected error values: %+v", actionErr)
+	}
+}
+
+func TestClientV3Policy(t *testing.T) {
+	server := newTestServer(t, jsonReply(`{"success": true, "score": 0.3, "action": "login"}`))
+	defer server.Close()
+	client := recaptcha.NewClient(
+		recaptcha.WithSecret(apiSecret),
+		recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithV3Policy(recaptcha.V3Policy{Action: "login", Threshold: 0.5}),
+	)
+	resp := client.VerifyV3(gResponse, "")
+	if resp.Success {
+		t.Error("response.Success should be false but it was not")
+	}
+	if len(resp.Errors) != 1 || !errors.Is(resp.Errors[0], recaptcha.ErrScoreTooLow) {
+		t.Errorf("the errors array should contain ErrScoreTooLow but it contains: %+v", resp.Errors)
+	}
+
+	// the policy does not apply to Recaptcha v2 responses
+	if resp := client.Verify(gResponse, ""); !resp.Success {
+		t.Errorf("the v2 response should be successful but it contains: %+v", resp.Errors)
+	}
+}
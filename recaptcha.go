@@ -16,19 +16,31 @@ import (
 // HTTPClient is the client used by lib, normally you don't need to modify it
 // but it can be modified to alter the tiemout, to reuse another client, etc.
 // Remember http.Client is thread-safe by default
+//
+// Deprecated: HTTPClient is shared by the whole program, use NewClient with WithHTTPClient instead
 var HTTPClient = &http.Client{Timeout: 10 * time.Second}
 
-const verifyURL = "https://www.google.com/recaptcha/api/siteverify"
-
 // Response represents the reCAPTCHA v2/Invisible reCAPTCHA verification Response
 type Response struct {
 	// Wether the user captcha response is valid or not
 	Success bool `json:"success"`
 	// timestamp of the challenge load (ISO format yyyy-MM-dd'T'HH:mm:ssZZ)
 	ChallengeTimeStamp string `json:"challenge_ts"`
+	// the hostname of the site where the reCAPTCHA was solved (web keys only)
+	Hostname string `json:"hostname,omitempty"`
+	// the package name of the app where the reCAPTCHA was solved (Android keys only)
+	ApkPackageName string `json:"apk_package_name,omitempty"`
 	// Errors, the user errors are represented by the UserError type, all Recaptcha are present as global variables in this package
-	// Other technical errors can be contained in this slice as for example, connection errors
+	// Other technical errors can be contained in this slice as for example, connection errors.
+	// The Check functions report those technical errors separately, leaving here only the API error codes
 	Errors Errors `json:"error-codes"`
+	// Cached is true when the response was not requested to the API but reused from a previous verification,
+	// see WithIdempotencyWindow
+	Cached bool `json:"-"`
+	// Attempts is the number of requests sent to the API to get this response, see WithRetry
+	Attempts int `json:"-"`
+	// Degraded is true when the API was unavailable and the response was synthesized, see FailOpen
+	Degraded bool `json:"-"`
 }
 
 // ResponseV3 represents the reCAPTCHA v3 verification response
@@ -56,7 +68,7 @@ func Verify(secret, clientResponse, remoteIP string) (response Response) {
 //  - clientResponse The user response token provided by the reCAPTCHA client-side integration of your app
 //  - remoteIP (optional) the user's IP, if provided Recaptcha will check if the user resolved the captcha with same IP
 func VerifyWithContext(ctx context.Context, secret, clientResponse, remoteIP string) (response Response) {
-	err := verify(ctx, secret, clientResponse, remoteIP, &response)
+	err := defaultClient.verify(ctx, secret, clientResponse, remoteIP, &response)
 	if err != nil {
 		response.Errors = []error{err}
 	}
@@ -79,74 +91,212 @@ func VerifyV3(secret, clientResponse, remoteIP string) (response ResponseV3) {
 //  - clientResponse The user response token provided by the reCAPTCHA client-side integration of your app
 //  - remoteIP (optional) The user's IP address, if provided Recaptcha will check if the user resolved the captcha with same IP
 func VerifyV3WithContext(ctx context.Context, secret, clientResponse, remoteIP string) (response ResponseV3) {
-	err := verify(ctx, secret, clientResponse, remoteIP, &response)
+	err := defaultClient.verify(ctx, secret, clientResponse, remoteIP, &response)
 	if err != nil {
 		response.Errors = []error{err}
 	}
 	return response
 }
 
+// Check verifies an user's Recaptcha v2/Invisible response like VerifyWithContext, but reports
+// transport and protocol failures through the returned error instead of mixing them into response.Errors.
+// When err is nil, response.Errors only contains the error codes reported by the API
+// Parameters:
+//  - ctx Provides context for cancelation
+//  - secret The Recaptcha API secret key
+//  - clientResponse The user response token provided by the reCAPTCHA client-side integration of your app
+//  - remoteIP (optional) the user's IP, if provided Recaptcha will check if the user resolved the captcha with same IP
+func Check(ctx context.Context, secret, clientResponse, remoteIP string) (response Response, err error) {
+	err = defaultClient.verify(ctx, secret, clientResponse, remoteIP, &response)
+	return response, err
+}
+
+// CheckV3 is the Recaptcha v3 counterpart of Check
+// Parameters:
+//  - ctx Provides context for cancelation
+//  - secret The Recaptcha API secret key
+//  - clientResponse The user response token provided by the reCAPTCHA client-side integration of your app
+//  - remoteIP (optional) The user's IP address, if provided Recaptcha will check if the user resolved the captcha with same IP
+func CheckV3(ctx context.Context, secret, clientResponse, remoteIP string) (response ResponseV3, err error) {
+	err = defaultClient.verify(ctx, secret, clientResponse, remoteIP, &response)
+	return response, err
+}
+
+// timeStampLayouts holds the formats in which the challenge timestamp may come,
+// besides RFC 3339 offsets without colon (yyyy-MM-dd'T'HH:mm:ssZZ) are accepted
+var timeStampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+}
+
 // ParseTimeStamp transforms a Recaptcha ChallengeTimeStamp string into a time.Time
-func ParseTimeStamp(ts string) (time.Time, error) {
+func ParseTimeStamp(ts string) (t time.Time, err error) {
+	for _, layout := range timeStampLayouts {
+		if t, err = time.Parse(layout, ts); err == nil {
+			return t, nil
+		}
+	}
 	return time.Parse(time.RFC3339, ts)
 }
 
-func verify(ctx context.Context, secret, clientResponse, remoteIP string, result interface{}) error {
+// verdict is implemented by every response type embedding Response
+type verdict interface {
+	response() *Response
+}
+
+func (r *Response) response() *Response {
+	return r
+}
+
+// scoredVerdict is implemented by every response type embedding ResponseV3
+type scoredVerdict interface {
+	responseV3() *ResponseV3
+}
+
+func (r *ResponseV3) responseV3() *ResponseV3 {
+	return r
+}
+
+// maxErrorBodySize limits how much of an unexpected response body is kept in the returned errors
+const maxErrorBodySize = 512
+
+func (c *Client) verify(ctx context.Context, secret, clientResponse, remoteIP string, result verdict) error {
+	return c.verifyWithParams(ctx, secret, clientResponse, remoteIP, nil, false, result)
+}
+
+// verifyWithParams verifies the user response sending the given form parameters along with the usual ones.
+// The replay guard is skipped when reusable is set, for the responses the provider accepts several times
+func (c *Client) verifyWithParams(ctx context.Context, secret, clientResponse, remoteIP string, params url.Values, reusable bool, result verdict) error {
 	if secret == "" {
-		return ErrInvalidInputSecret
+		result.response().Errors = []error{ErrInvalidInputSecret}
+		return nil
 	}
 	if clientResponse == "" {
-		return ErrInvalidInputResponse
+		result.response().Errors = []error{ErrInvalidInputResponse}
+		return nil
 	}
 
-	response, err := sendVerifyHTTPRequest(ctx, secret, clientResponse, remoteIP)
-	if err != nil {
-		return err
+	call := func() error {
+		return c.siteverify(ctx, secret, clientResponse, remoteIP, params, result)
 	}
-	defer response.Body.Close()
+	fingerprint := secret + "|" + params.Encode()
+	if reusable {
+		return c.deduplicate(ctx, clientResponse, remoteIP, fingerprint, result, call)
+	}
+	return c.protect(ctx, clientResponse, remoteIP, fingerprint, result, call)
+}
+
+// protect runs the API call verifying the user response behind the idempotency cache and the replay guard,
+// fingerprint holds the other parameters of the request which must match to reuse a cached verdict
+func (c *Client) protect(ctx context.Context, clientResponse, remoteIP, fingerprint string, result verdict, call func() error) error {
+	return c.deduplicate(ctx, clientResponse, remoteIP, fingerprint, result, func() error {
+		return c.guardReplay(ctx, clientResponse, result, call)
+	})
+}
 
-	return unmarshalJSONBody(response.Body, result)
+// deduplicate runs the API call verifying the user response behind the idempotency cache only
+func (c *Client) deduplicate(ctx context.Context, clientResponse, remoteIP, fingerprint string, result verdict, call func() error) error {
+	if c.idempotency != nil {
+		return c.idempotency.do(ctx, c.now, clientResponse, remoteIP, fingerprint, result, call)
+	}
+	return call()
 }
 
-func sendVerifyHTTPRequest(ctx context.Context, secret, clientResponse, remoteIP string) (*http.Response, error) {
+// siteverify asks the API about the user response and checks the verdict against the client expectations
+func (c *Client) siteverify(ctx context.Context, secret, clientResponse, remoteIP string, params url.Values, result verdict) error {
 	data := url.Values{}
+	for key, values := range c.params {
+		data[key] = values
+	}
+	for key, values := range params {
+		data[key] = values
+	}
 	data.Set("secret", secret)
 	data.Set("response", clientResponse)
 	if remoteIP != "" {
 		data.Set("remoteip", remoteIP)
 	}
+	attempts, err := c.call(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.verifyURL, strings.NewReader(data.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	}, result)
+	result.response().Attempts = attempts
+	if err != nil {
+		if c.degrade(err, result) {
+			return nil
+		}
+		return err
+	}
+	c.checkVerdict(result)
+	return nil
+}
+
+// checkVerdict checks a verdict returned by the API against the client expectations
+func (c *Client) checkVerdict(result verdict) {
+	c.checkExpectations(result.response())
+	c.checkFreshness(result.response())
+	if scored, ok := result.(scoredVerdict); ok && c.v3Policy != nil {
+		scored.responseV3().ApplyPolicy(*c.v3Policy)
+	}
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, verifyURL, strings.NewReader(data.Encode()))
+// sendHTTPRequest performs a single attempt of an API call and returns the response body
+func (c *Client) sendHTTPRequest(ctx context.Context, newRequest func(context.Context) (*http.Request, error)) ([]byte, error) {
+	req, err := newRequest(ctx)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	response, err := HTTPClient.Do(req)
-
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	response, err := checkHTTPResponse(c.client().Do(req))
 	if err != nil {
 		return nil, err
 	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, &TransportError{Err: fmt.Errorf("unable to read response body %w", err)}
+	}
+	return body, nil
+}
+
+// checkHTTPResponse turns the outcome of an http.Client call into the typed errors of this package,
+// the response body is closed when an error is returned
+func checkHTTPResponse(response *http.Response, err error) (*http.Response, error) {
+	if err != nil {
+		return nil, &TransportError{Err: err}
+	}
 
 	if response.StatusCode < 200 || response.StatusCode > 299 {
-		return nil, fmt.Errorf("unexpected response code %d", response.StatusCode)
+		defer response.Body.Close()
+		return nil, &HTTPStatusError{
+			StatusCode: response.StatusCode,
+			Body:       readErrorBody(response.Body),
+			RetryAfter: parseRetryAfter(response.Header.Get("Retry-After")),
+		}
 	}
 	if contentType := response.Header.Get("Content-Type"); !strings.Contains(contentType, "application/json") {
-		return nil, fmt.Errorf("Unexpected response Content-Type: %s", contentType)
+		defer response.Body.Close()
+		return nil, &ContentTypeError{ContentType: contentType, Body: readErrorBody(response.Body)}
 	}
 
 	return response, nil
 }
 
-func unmarshalJSONBody(body io.Reader, target interface{}) error {
-	bodyContent, err := ioutil.ReadAll(body)
-	if err != nil {
-		return fmt.Errorf("unable to read response body %w", err)
-	}
+func readErrorBody(body io.Reader) string {
+	content, _ := ioutil.ReadAll(io.LimitReader(body, maxErrorBodySize))
+	return string(content)
+}
 
-	err = json.Unmarshal(bodyContent, target)
-	if err != nil {
-		return fmt.Errorf("Error unmarshalling the response body: %w", err)
+func unmarshalJSONBody(body []byte, target interface{}) error {
+	if err := json.Unmarshal(body, target); err != nil {
+		return &DecodeError{Err: err}
 	}
-
 	return nil
 }
@@ -413,10 +413,26 @@ func TestParseTimeStamp(t *testing.T) {
 	tsStr := "2020-08-16T12:18:29Z"
 	ts, err := recaptcha.ParseTimeStamp(tsStr)
 	if err != nil {
-		t.Errorf("unexpected error occurred: %w", err)
+		t.Errorf("unexpected error occurred: %v", err)
 	}
 	expectedTS := time.Date(2020, 8, 16, 12, 18, 29, 0, time.UTC)
 	if !ts.Equal(expectedTS) {
 		t.Errorf("The date was expected to be \"%v\" but got \"%v\"", expectedTS, ts)
 	}
 }
+
+func TestParseTimeStampOffsets(t *testing.T) {
+	expectedTS := time.Date(2020, 8, 16, 12, 18, 29, 0, time.UTC)
+	for _, tsStr := range []string{"2020-08-16T14:18:29+02:00", "2020-08-16T14:18:29+0200", "2020-08-16T10:18:29-0200", "2020-08-16T12:18:29.000Z"} {
+		ts, err := recaptcha.ParseTimeStamp(tsStr)
+		if err != nil {
+			t.Errorf("unexpected error occurred parsing %s: %v", tsStr, err)
+		}
+		if !ts.Equal(expectedTS) {
+			t.Errorf("The date was expected to be \"%v\" but got \"%v\"", expectedTS, ts)
+		}
+	}
+	if _, err := recaptcha.ParseTimeStamp("yesterday"); err == nil {
+		t.Error("an error was expected parsing an invalid timestamp")
+	}
+}
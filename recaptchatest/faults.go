@@ -0,0 +1,167 @@
+package recaptchatest
+
+import (
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Fault is a way the fake endpoint misbehaves
+type Fault string
+
+// The faults which can be injected
+const (
+	// FaultServerError replies with a 5xx status code
+	FaultServerError Fault = "server_error"
+	// FaultRateLimit replies 429 Too Many Requests with a Retry-After header
+	FaultRateLimit Fault = "rate_limit"
+	// FaultHTMLPage replies 200 OK with an HTML error page
+	FaultHTMLPage Fault = "html_page"
+	// FaultTruncatedJSON replies with the beginning of a JSON body
+	FaultTruncatedJSON Fault = "truncated_json"
+	// FaultSlowDrip sends a valid answer one byte at a time
+	FaultSlowDrip Fault = "slow_drip"
+	// FaultConnectionReset resets the connection without answering
+	FaultConnectionReset Fault = "connection_reset"
+)
+
+// faults lists the faults in the order used to pick them
+var faults = []Fault{FaultServerError, FaultRateLimit, FaultHTMLPage, FaultTruncatedJSON, FaultSlowDrip, FaultConnectionReset}
+
+// FaultProfile makes the endpoint misbehave at random, see Siteverify.SetFaults
+type FaultProfile struct {
+	// Probabilities holds the probability of every fault, their sum should not exceed 1.
+	// The requests which are not faulted are answered normally
+	Probabilities map[Fault]float64
+	// StatusCode is the status code of FaultServerError, 503 by default
+	StatusCode int
+	// RetryAfter is the delay sent by FaultRateLimit, 1 second by default
+	RetryAfter time.Duration
+	// DripInterval is the delay between the bytes of FaultSlowDrip, 50 milliseconds by default
+	DripInterval time.Duration
+	// Seed makes the faults reproducible, a random seed is used if it is 0
+	Seed int64
+}
+
+// Counters tells how the endpoint answered the requests
+type Counters struct {
+	Requests int `json:"requests"`
+	// Verdicts counts the requests answered normally
+	Verdicts int `json:"verdicts"`
+	// Faults counts the requests which got each fault
+	Faults map[Fault]int `json:"faults"`
+}
+
+// SetFaults makes the endpoint inject faults following the profile. Like on a real network, the faults
+// damaging the answer (FaultTruncatedJSON and FaultSlowDrip) happen after the token is used while the others
+// happen before
+func (s *Siteverify) SetFaults(profile FaultProfile) {
+	if profile.StatusCode == 0 {
+		profile.StatusCode = http.StatusServiceUnavailable
+	}
+	if profile.RetryAfter <= 0 {
+		profile.RetryAfter = time.Second
+	}
+	if profile.DripInterval <= 0 {
+		profile.DripInterval = 50 * time.Millisecond
+	}
+	seed := profile.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = &profile
+	s.random = rand.New(rand.NewSource(seed))
+}
+
+// Counters returns the counters of the requests received so far
+func (s *Siteverify) Counters() Counters {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counters := s.counters
+	counters.Faults = map[Fault]int{}
+	for fault, n := range s.counters.Faults {
+		counters.Faults[fault] = n
+	}
+	return counters
+}
+
+// ResetCounters zeroes the counters
+func (s *Siteverify) ResetCounters() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counters = Counters{Faults: map[Fault]int{}}
+}
+
+// pickFault counts a request and returns the fault it gets, if any
+func (s *Siteverify) pickFault() (Fault, *FaultProfile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counters.Requests++
+	if s.faults == nil {
+		s.counters.Verdicts++
+		return "", nil
+	}
+
+	draw := s.random.Float64()
+	for _, fault := range faults {
+		draw -= s.faults.Probabilities[fault]
+		if draw < 0 {
+			s.counters.Faults[fault]++
+			return fault, s.faults
+		}
+	}
+	s.counters.Verdicts++
+	return "", nil
+}
+
+// inject sends the answer of a fault, body is the answer the request would get otherwise
+func inject(w http.ResponseWriter, r *http.Request, fault Fault, profile *FaultProfile, body []byte) {
+	switch fault {
+	case FaultServerError:
+		http.Error(w, http.StatusText(profile.StatusCode), profile.StatusCode)
+	case FaultRateLimit:
+		seconds := int((profile.RetryAfter + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+	case FaultHTMLPage:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<!DOCTYPE html><html><head><title>Error</title></head><body><h1>Server Error</h1></body></html>"))
+	case FaultTruncatedJSON:
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(body[:len(body)/2])
+	case FaultSlowDrip:
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		flusher, _ := w.(http.Flusher)
+		for i := range body {
+			select {
+			case <-time.After(profile.DripInterval):
+			case <-r.Context().Done():
+				return
+			}
+			w.Write(body[i : i+1])
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	case FaultConnectionReset:
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "connection resets are not supported", http.StatusInternalServerError)
+			return
+		}
+		conn, _, err := hijacker.Hijack()
+		if err != nil {
+			return
+		}
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			// closing with a zero linger sends a RST instead of a FIN
+			tcp.SetLinger(0)
+		}
+		conn.Close()
+	}
+}
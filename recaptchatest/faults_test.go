@@ -0,0 +1,134 @@
+package recaptchatest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/claudio4/go-recaptcha"
+	"github.com/claudio4/go-recaptcha/recaptchatest"
+)
+
+func TestFaults(t *testing.T) {
+	server := recaptchatest.NewServer()
+	defer server.Close()
+	client := server.NewClient()
+
+	tests := []struct {
+		fault recaptchatest.Fault
+		check func(err error) bool
+	}{
+		{recaptchatest.FaultServerError, func(err error) bool {
+			var statusErr *recaptcha.HTTPStatusError
+			return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusBadGateway
+		}},
+		{recaptchatest.FaultRateLimit, func(err error) bool {
+			var statusErr *recaptcha.HTTPStatusError
+			return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusTooManyRequests && statusErr.RetryAfter == 2*time.Second
+		}},
+		{recaptchatest.FaultHTMLPage, func(err error) bool {
+			var ctErr *recaptcha.ContentTypeError
+			return errors.As(err, &ctErr)
+		}},
+		{recaptchatest.FaultTruncatedJSON, func(err error) bool {
+			var decodeErr *recaptcha.DecodeError
+			return errors.As(err, &decodeErr)
+		}},
+		{recaptchatest.FaultConnectionReset, func(err error) bool {
+			var transportErr *recaptcha.TransportError
+			return errors.As(err, &transportErr)
+		}},
+	}
+	for _, tc := range tests {
+		server.Reset()
+		server.Register("token", recaptchatest.Verdict{Success: true})
+		server.SetFaults(recaptchatest.FaultProfile{
+			Probabilities: map[recaptchatest.Fault]float64{tc.fault: 1},
+			StatusCode:    http.StatusBadGateway,
+			RetryAfter:    1500 * time.Millisecond,
+		})
+		if _, err := client.Check(context.Background(), "token", ""); !tc.check(err) {
+			t.Errorf("%s: unexpected error %v", tc.fault, err)
+		}
+		if counters := server.Counters(); counters.Requests != 1 || counters.Faults[tc.fault] != 1 || counters.Verdicts != 0 {
+			t.Errorf("%s: unexpected counters %+v", tc.fault, counters)
+		}
+	}
+}
+
+func TestFaultSlowDrip(t *testing.T) {
+	server := recaptchatest.NewServer()
+	defer server.Close()
+	server.SetDefault(recaptchatest.Verdict{Success: true})
+	client := server.NewClient()
+
+	server.SetFaults(recaptchatest.FaultProfile{
+		Probabilities: map[recaptchatest.Fault]float64{recaptchatest.FaultSlowDrip: 1},
+		DripInterval:  time.Millisecond,
+	})
+	if resp, err := client.Check(context.Background(), "token", ""); err != nil || !resp.Success {
+		t.Errorf("a slow answer should be valid once received but got %+v, %v", resp, err)
+	}
+
+	server.SetFaults(recaptchatest.FaultProfile{
+		Probabilities: map[recaptchatest.Fault]float64{recaptchatest.FaultSlowDrip: 1},
+		DripInterval:  time.Second,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := client.Check(ctx, "token", ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("the verification should time out but got %v", err)
+	}
+}
+
+func TestFaultProfileProbabilities(t *testing.T) {
+	run := func() recaptchatest.Counters {
+		server := recaptchatest.NewServer()
+		defer server.Close()
+		server.SetDefault(recaptchatest.Verdict{Success: true})
+		server.SetFaults(recaptchatest.FaultProfile{
+			Probabilities: map[recaptchatest.Fault]float64{
+				recaptchatest.FaultServerError: 0.3,
+				recaptchatest.FaultRateLimit:   0.2,
+			},
+			Seed: 42,
+		})
+		client := server.NewClient()
+		for i := 0; i < 200; i++ {
+			client.Check(context.Background(), "token", "")
+		}
+		return server.Counters()
+	}
+
+	counters := run()
+	if counters.Requests != 200 || counters.Verdicts+counters.Faults[recaptchatest.FaultServerError]+counters.Faults[recaptchatest.FaultRateLimit] != 200 {
+		t.Fatalf("every request should be counted once: %+v", counters)
+	}
+	if n := counters.Faults[recaptchatest.FaultServerError]; n < 35 || n > 85 {
+		t.Errorf("about 60 server errors were expected but got %d", n)
+	}
+	if n := counters.Faults[recaptchatest.FaultRateLimit]; n < 20 || n > 60 {
+		t.Errorf("about 40 rate limits were expected but got %d", n)
+	}
+	if again := run(); again.Faults[recaptchatest.FaultServerError] != counters.Faults[recaptchatest.FaultServerError] ||
+		again.Verdicts != counters.Verdicts {
+		t.Errorf("the same seed should produce the same faults: %+v and %+v", counters, again)
+	}
+}
+
+func TestFaultsFailOpen(t *testing.T) {
+	server := recaptchatest.NewServer()
+	defer server.Close()
+	server.SetFaults(recaptchatest.FaultProfile{Probabilities: map[recaptchatest.Fault]float64{recaptchatest.FaultServerError: 1}})
+
+	failOpen := server.NewClient(recaptcha.WithDegradationMode(recaptcha.FailOpen))
+	if resp, err := failOpen.Check(context.Background(), "token", ""); err != nil || !resp.Success || !resp.Degraded {
+		t.Errorf("a fail-open client should accept the response but got %+v, %v", resp, err)
+	}
+	failClosed := server.NewClient()
+	if resp, err := failClosed.Check(context.Background(), "token", ""); err == nil || resp.Success {
+		t.Errorf("a fail-closed client should report the failure but got %+v, %v", resp, err)
+	}
+}
@@ -0,0 +1,209 @@
+// Package recaptchatest provides a fake siteverify endpoint to test the code using the recaptcha package
+// without reaching Google's servers. The user responses it accepts are scripted with Register:
+//
+//	server := recaptchatest.NewServer()
+//	defer server.Close()
+//	server.Register("good-token", recaptchatest.Verdict{Success: true, Score: 0.9, Action: "login"})
+//	client := server.NewClient()
+package recaptchatest
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+// Secret is the secret accepted by the servers created with NewServer
+const Secret = "recaptchatest-secret"
+
+// Verdict is the answer of the fake endpoint for a user response
+type Verdict struct {
+	Success bool
+	// Score and Action are only sent when one of them is set, like Recaptcha v3 does
+	Score  float64
+	Action string
+	// Hostname is "localhost" if empty and neither ApkPackageName is set
+	Hostname       string
+	ApkPackageName string
+	// ChallengeTimeStamp is the time the request is answered if it is zero
+	ChallengeTimeStamp time.Time
+	// ErrorCodes are the codes sent in error-codes, e.g. "timeout-or-duplicate"
+	ErrorCodes []string
+	// Latency delays the answer, the request is abandoned if its context ends before
+	Latency time.Duration
+	// RemoteIP (optional) is the only remote IP accepted along with the response
+	RemoteIP string
+	// Reusable allows to verify the response several times, otherwise the next verifications fail
+	// with timeout-or-duplicate like the real endpoint does
+	Reusable bool
+}
+
+// Siteverify is a fake siteverify endpoint which answers with the verdicts registered for the user responses,
+// unknown responses are rejected with invalid-input-response. It is safe for concurrent use
+type Siteverify struct {
+	secret string
+	now    func() time.Time
+
+	mu       sync.Mutex
+	tokens   map[string]Verdict
+	used     map[string]bool
+	fallback *Verdict
+	faults   *FaultProfile
+	random   *rand.Rand
+	counters Counters
+}
+
+// NewSiteverify creates a fake siteverify endpoint which accepts the given secret
+func NewSiteverify(secret string) *Siteverify {
+	return &Siteverify{
+		secret:   secret,
+		now:      time.Now,
+		tokens:   map[string]Verdict{},
+		used:     map[string]bool{},
+		counters: Counters{Faults: map[Fault]int{}},
+	}
+}
+
+// Register sets the verdict of a user response, replacing the previous one
+func (s *Siteverify) Register(clientResponse string, verdict Verdict) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[clientResponse] = verdict
+	delete(s.used, clientResponse)
+}
+
+// SetDefault sets the verdict of the user responses which are not registered
+func (s *Siteverify) SetDefault(verdict Verdict) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fallback = &verdict
+}
+
+// Reset forgets the registered responses, the default verdict and the faults, and zeroes the counters
+func (s *Siteverify) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = map[string]Verdict{}
+	s.used = map[string]bool{}
+	s.fallback = nil
+	s.faults = nil
+	s.counters = Counters{Faults: map[Fault]int{}}
+}
+
+// verdict returns the verdict for a verification and marks single use responses as used
+func (s *Siteverify) verdict(secret, clientResponse, remoteIP string) Verdict {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if secret != s.secret {
+		return Verdict{ErrorCodes: []string{"invalid-input-secret"}}
+	}
+	verdict, ok := s.tokens[clientResponse]
+	if !ok && s.fallback != nil {
+		return *s.fallback
+	}
+	if !ok || clientResponse == "" {
+		return Verdict{ErrorCodes: []string{"invalid-input-response"}}
+	}
+	if verdict.RemoteIP != "" && verdict.RemoteIP != remoteIP {
+		return Verdict{Latency: verdict.Latency, ErrorCodes: []string{"invalid-input-response"}}
+	}
+	if s.used[clientResponse] {
+		return Verdict{Latency: verdict.Latency, ErrorCodes: []string{"timeout-or-duplicate"}}
+	}
+	if !verdict.Reusable {
+		s.used[clientResponse] = true
+	}
+	return verdict
+}
+
+type siteverifyResponse struct {
+	Success            bool     `json:"success"`
+	ChallengeTimeStamp string   `json:"challenge_ts,omitempty"`
+	Hostname           string   `json:"hostname,omitempty"`
+	ApkPackageName     string   `json:"apk_package_name,omitempty"`
+	Score              *float64 `json:"score,omitempty"`
+	Action             string   `json:"action,omitempty"`
+	ErrorCodes         []string `json:"error-codes,omitempty"`
+}
+
+// ServeHTTP answers a siteverify request
+func (s *Siteverify) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	fault, profile := s.pickFault()
+	if fault != "" && fault != FaultTruncatedJSON && fault != FaultSlowDrip {
+		inject(w, r, fault, profile, nil)
+		return
+	}
+
+	var response siteverifyResponse
+	if err := r.ParseForm(); err != nil {
+		response = siteverifyResponse{ErrorCodes: []string{"bad-request"}}
+	} else {
+		verdict := s.verdict(r.PostForm.Get("secret"), r.PostForm.Get("response"), r.PostForm.Get("remoteip"))
+		if verdict.Latency > 0 {
+			timer := time.NewTimer(verdict.Latency)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		response = s.answer(verdict)
+	}
+
+	body, _ := json.Marshal(response)
+	if fault != "" {
+		inject(w, r, fault, profile, body)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(body)
+}
+
+// answer returns the siteverify answer of a verdict
+func (s *Siteverify) answer(verdict Verdict) siteverifyResponse {
+	response := siteverifyResponse{Success: verdict.Success, ErrorCodes: verdict.ErrorCodes}
+	if verdict.Success {
+		timestamp := verdict.ChallengeTimeStamp
+		if timestamp.IsZero() {
+			timestamp = s.now()
+		}
+		response.ChallengeTimeStamp = timestamp.UTC().Format(time.RFC3339)
+		response.Hostname, response.ApkPackageName = verdict.Hostname, verdict.ApkPackageName
+		if response.Hostname == "" && response.ApkPackageName == "" {
+			response.Hostname = "localhost"
+		}
+	}
+	if verdict.Score != 0 || verdict.Action != "" {
+		score := verdict.Score
+		response.Score, response.Action = &score, verdict.Action
+	}
+	return response
+}
+
+// Server is a Siteverify endpoint listening on a local port, it is created with NewServer and must be closed
+type Server struct {
+	*Siteverify
+	*httptest.Server
+}
+
+// NewServer starts a fake siteverify endpoint accepting Secret
+func NewServer() *Server {
+	siteverify := NewSiteverify(Secret)
+	return &Server{Siteverify: siteverify, Server: httptest.NewServer(siteverify)}
+}
+
+// NewClient creates a recaptcha.Client pointed at the server with Secret, the options can override them
+func (s *Server) NewClient(opts ...recaptcha.Option) *recaptcha.Client {
+	opts = append([]recaptcha.Option{recaptcha.WithSecret(Secret), recaptcha.WithVerifyURL(s.URL)}, opts...)
+	return recaptcha.NewClient(opts...)
+}
@@ -0,0 +1,112 @@
+package recaptchatest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claudio4/go-recaptcha"
+	"github.com/claudio4/go-recaptcha/recaptchatest"
+)
+
+func TestServer(t *testing.T) {
+	server := recaptchatest.NewServer()
+	defer server.Close()
+	server.Register("good", recaptchatest.Verdict{Success: true, Score: 0.9, Action: "login", Hostname: "example.com"})
+	server.Register("bot", recaptchatest.Verdict{Success: true, Score: 0.1, Action: "login"})
+	server.Register("expired", recaptchatest.Verdict{ErrorCodes: []string{"timeout-or-duplicate"}})
+	client := server.NewClient(recaptcha.WithAllowedHostnames("example.com", "localhost"))
+
+	resp, err := client.CheckV3(context.Background(), "good", "")
+	if err != nil || !resp.Success || resp.Score != 0.9 || resp.Action != "login" || resp.Hostname != "example.com" {
+		t.Errorf("unexpected response %+v, %v", resp, err)
+	}
+	if _, err := recaptcha.ParseTimeStamp(resp.ChallengeTimeStamp); err != nil {
+		t.Errorf("the challenge timestamp should be valid: %v", err)
+	}
+
+	resp, err = client.CheckV3(context.Background(), "good", "")
+	if err != nil || resp.Success || len(resp.Errors) != 1 || resp.Errors[0] != recaptcha.ErrTimeoutOrDuplicate {
+		t.Errorf("a response should only be accepted once but got %+v, %v", resp, err)
+	}
+
+	resp, _ = client.CheckV3(context.Background(), "bot", "")
+	if !resp.Success || resp.Score != 0.1 {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	resp, _ = client.CheckV3(context.Background(), "expired", "")
+	if resp.Success || len(resp.Errors) != 1 || resp.Errors[0] != recaptcha.ErrTimeoutOrDuplicate {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	resp, _ = client.CheckV3(context.Background(), "unknown", "")
+	if resp.Success || len(resp.Errors) != 1 || resp.Errors[0] != recaptcha.ErrInvalidInputResponse {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	wrongSecret := server.NewClient(recaptcha.WithSecret("other"))
+	server.Register("good", recaptchatest.Verdict{Success: true})
+	if resp, _ := wrongSecret.Check(context.Background(), "good", ""); len(resp.Errors) != 1 || resp.Errors[0] != recaptcha.ErrInvalidInputSecret {
+		t.Errorf("the secret should be checked but got %+v", resp)
+	}
+}
+
+func TestServerRemoteIPAndReuse(t *testing.T) {
+	server := recaptchatest.NewServer()
+	defer server.Close()
+	server.Register("token", recaptchatest.Verdict{Success: true, RemoteIP: "192.0.2.1", Reusable: true})
+	client := server.NewClient()
+
+	if resp, _ := client.Check(context.Background(), "token", "192.0.2.2"); resp.Success {
+		t.Error("the response should be rejected from another IP")
+	}
+	for i := 0; i < 2; i++ {
+		if resp, _ := client.Check(context.Background(), "token", "192.0.2.1"); !resp.Success {
+			t.Errorf("a reusable response should be accepted every time but got %+v", resp)
+		}
+	}
+
+	server.Reset()
+	server.SetDefault(recaptchatest.Verdict{Success: true})
+	if resp, _ := client.Check(context.Background(), "anything", ""); !resp.Success {
+		t.Errorf("the default verdict should be used but got %+v", resp)
+	}
+}
+
+func TestServerLatency(t *testing.T) {
+	server := recaptchatest.NewServer()
+	defer server.Close()
+	server.Register("slow", recaptchatest.Verdict{Success: true, Latency: 300 * time.Millisecond})
+	client := server.NewClient()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.Check(ctx, "slow", "")
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 250*time.Millisecond {
+		t.Errorf("the verification should time out but got %v after %s", err, time.Since(start))
+	}
+}
+
+func TestServerMiddleware(t *testing.T) {
+	server := recaptchatest.NewServer()
+	defer server.Close()
+	server.Register("human", recaptchatest.Verdict{Success: true})
+	handler := recaptcha.Middleware(server.NewClient(), recaptcha.MiddlewareOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for token, status := range map[string]int{"human": http.StatusNoContent, "robot": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{recaptcha.DefaultFormField: {token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != status {
+			t.Errorf("%s: the status should be %d but it was %d", token, status, rec.Code)
+		}
+	}
+}
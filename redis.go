@@ -0,0 +1,256 @@
+package recaptcha
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// RedisReplayStore is a ReplayStore backed by a Redis server, so every instance of an application
+// shares the same replay protection. It speaks the Redis protocol directly to avoid external dependencies
+type RedisReplayStore struct {
+	addr     string
+	password string
+	db       int
+	prefix   string
+	timeout  time.Duration
+	idle     chan *redisConn
+}
+
+// RedisOption configures a RedisReplayStore
+type RedisOption func(*RedisReplayStore)
+
+// WithRedisPassword sets the password used to authenticate the connections
+func WithRedisPassword(password string) RedisOption {
+	return func(s *RedisReplayStore) {
+		s.password = password
+	}
+}
+
+// WithRedisDB selects the logical database used by the store
+func WithRedisDB(db int) RedisOption {
+	return func(s *RedisReplayStore) {
+		s.db = db
+	}
+}
+
+// WithRedisKeyPrefix sets the prefix of the keys written by the store ("recaptcha:replay:" by default)
+func WithRedisKeyPrefix(prefix string) RedisOption {
+	return func(s *RedisReplayStore) {
+		s.prefix = prefix
+	}
+}
+
+// WithRedisTimeout sets the dial, read and write timeout of every command (5 seconds by default)
+func WithRedisTimeout(timeout time.Duration) RedisOption {
+	return func(s *RedisReplayStore) {
+		s.timeout = timeout
+	}
+}
+
+// WithRedisMaxIdleConns sets how many connections are kept open between commands (4 by default)
+func WithRedisMaxIdleConns(n int) RedisOption {
+	return func(s *RedisReplayStore) {
+		s.idle = make(chan *redisConn, n)
+	}
+}
+
+// NewRedisReplayStore creates a RedisReplayStore for the server listening on addr (host:port).
+// Connections are opened lazily
+func NewRedisReplayStore(addr string, opts ...RedisOption) *RedisReplayStore {
+	s := &RedisReplayStore{
+		addr:    addr,
+		prefix:  "recaptcha:replay:",
+		timeout: 5 * time.Second,
+		idle:    make(chan *redisConn, 4),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Reserve records key for ttl using SET NX, it reports false if the key already exists
+func (s *RedisReplayStore) Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ms := ttl.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	reply, err := s.do(ctx, "SET", s.prefix+key, "1", "PX", strconv.FormatInt(ms, 10), "NX")
+	if err != nil {
+		return false, err
+	}
+	return reply != nil, nil
+}
+
+// Release deletes key
+func (s *RedisReplayStore) Release(ctx context.Context, key string) error {
+	_, err := s.do(ctx, "DEL", s.prefix+key)
+	return err
+}
+
+// Close closes the idle connections of the store
+func (s *RedisReplayStore) Close() error {
+	for {
+		select {
+		case conn := <-s.idle:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (s *RedisReplayStore) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(ctx, s.timeout, args...)
+	var replyErr redisError
+	if err != nil && !errors.As(err, &replyErr) {
+		// the connection state is unknown after a network error
+		conn.Close()
+		return nil, err
+	}
+
+	select {
+	case s.idle <- conn:
+	default:
+		conn.Close()
+	}
+	return reply, err
+}
+
+func (s *RedisReplayStore) conn(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-s.idle:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: s.timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{Conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if s.password != "" {
+		if _, err := conn.do(ctx, s.timeout, "AUTH", s.password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.db != 0 {
+		if _, err := conn.do(ctx, s.timeout, "SELECT", strconv.Itoa(s.db)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// redisError is an error reply sent by the server
+type redisError string
+
+func (err redisError) Error() string {
+	return "redis: " + string(err)
+}
+
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// do sends a command and reads its reply, which is either a string, an int64, a []interface{} or nil
+func (c *redisConn) do(ctx context.Context, timeout time.Duration, args ...string) (interface{}, error) {
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := c.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := c.Write(buf); err != nil {
+		return nil, err
+	}
+	return readRedisReply(c.reader)
+}
+
+// readRedisReply reads a reply, a redisError means the reply was read entirely
+func readRedisReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, redisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		// an error element is returned once the whole array is read, so the connection can be reused,
+		// any other error leaves the connection in an unknown state and it is closed by the caller
+		items := make([]interface{}, size)
+		var elementErr redisError
+		for i := range items {
+			item, err := readRedisReply(r)
+			var replyErr redisError
+			switch {
+			case errors.As(err, &replyErr):
+				if elementErr == "" {
+					elementErr = replyErr
+				}
+			case err != nil:
+				return nil, err
+			}
+			items[i] = item
+		}
+		if elementErr != "" {
+			return nil, elementErr
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
@@ -0,0 +1,250 @@
+package recaptcha_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+// fakeRedis is a local stand-in of a Redis server which understands the commands used by RedisReplayStore,
+// it must be closed by the caller
+type fakeRedis struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	keys     map[string]time.Time
+	password string
+	commands []string
+}
+
+func newFakeRedis(t *testing.T, password string) (*fakeRedis, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+
+	fake := &fakeRedis{listener: listener, keys: make(map[string]time.Time), password: password}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go fake.serve(conn)
+		}
+	}()
+	return fake, listener.Addr().String()
+}
+
+// Close stops accepting connections
+func (f *fakeRedis) Close() error {
+	return f.listener.Close()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := f.password == ""
+	for {
+		args, err := readFakeRedisCommand(reader)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, strings.Join(args, " "))
+		var reply string
+		switch {
+		case strings.EqualFold(args[0], "AUTH"):
+			if args[1] == f.password {
+				authenticated = true
+				reply = "+OK\r\n"
+			} else {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authenticated:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case strings.EqualFold(args[0], "SELECT"):
+			reply = "+OK\r\n"
+		case strings.EqualFold(args[0], "SET"):
+			reply = f.set(args[1:])
+		case strings.EqualFold(args[0], "DEL"):
+			_, ok := f.keys[args[1]]
+			delete(f.keys, args[1])
+			if ok {
+				reply = ":1\r\n"
+			} else {
+				reply = ":0\r\n"
+			}
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		f.mu.Unlock()
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) set(args []string) string {
+	key, expiry, nx := args[0], time.Time{}, false
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "PX":
+			ms, _ := strconv.Atoi(args[i+1])
+			expiry = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			i++
+		}
+	}
+	if current, ok := f.keys[key]; nx && ok && time.Now().Before(current) {
+		return "$-1\r\n"
+	}
+	f.keys[key] = expiry
+	return "+OK\r\n"
+}
+
+func readFakeRedisCommand(reader *bufio.Reader) ([]string, error) {
+	var count int
+	if _, err := fmt.Fscanf(reader, "*%d\r\n", &count); err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		var size int
+		if _, err := fmt.Fscanf(reader, "$%d\r\n", &size); err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func TestRedisReplayStore(t *testing.T) {
+	fake, addr := newFakeRedis(t, "hunter2")
+	defer fake.Close()
+	store := recaptcha.NewRedisReplayStore(addr, recaptcha.WithRedisPassword("hunter2"), recaptcha.WithRedisDB(3))
+	defer store.Close()
+	ctx := context.Background()
+
+	if ok, err := store.Reserve(ctx, "a", time.Minute); !ok || err != nil {
+		t.Errorf("the first reservation should succeed but got %t, %v", ok, err)
+	}
+	if ok, err := store.Reserve(ctx, "a", time.Minute); ok || err != nil {
+		t.Errorf("the second reservation should fail but got %t, %v", ok, err)
+	}
+	if err := store.Release(ctx, "a"); err != nil {
+		t.Errorf("unexpected error occurred: %v", err)
+	}
+	if ok, err := store.Reserve(ctx, "a", time.Minute); !ok || err != nil {
+		t.Errorf("the reservation should succeed after a release but got %t, %v", ok, err)
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	expected := []string{"AUTH hunter2", "SELECT 3", "SET recaptcha:replay:a 1 PX 60000 NX"}
+	for i, command := range expected {
+		if fake.commands[i] != command {
+			t.Errorf("the command %d should be %q but it was %q", i, command, fake.commands[i])
+		}
+	}
+}
+
+func TestRedisReplayStoreWrongPassword(t *testing.T) {
+	fake, addr := newFakeRedis(t, "hunter2")
+	defer fake.Close()
+	store := recaptcha.NewRedisReplayStore(addr, recaptcha.WithRedisPassword("wrong"))
+	defer store.Close()
+	if _, err := store.Reserve(context.Background(), "a", time.Minute); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("a WRONGPASS error was expected but got %v", err)
+	}
+}
+
+func TestReplayGuardRedis(t *testing.T) {
+	fake, addr := newFakeRedis(t, "")
+	defer fake.Close()
+	store := recaptcha.NewRedisReplayStore(addr)
+	defer store.Close()
+	testReplayGuard(t, store)
+}
+
+// scriptedRedis answers the commands with the given raw replies, in order, and counts its connections
+func scriptedRedis(t *testing.T, replies ...string) (net.Listener, *int32) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	var conns, next int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&conns, 1)
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					if _, err := readFakeRedisCommand(reader); err != nil {
+						return
+					}
+					i := int(atomic.AddInt32(&next, 1)) - 1
+					if i >= len(replies) {
+						return
+					}
+					io.WriteString(conn, replies[i])
+				}
+			}()
+		}
+	}()
+	return listener, &conns
+}
+
+func TestRedisReplayStoreArrayErrors(t *testing.T) {
+	// an error element is read along with the rest of the array and the connection is reused
+	listener, conns := scriptedRedis(t, "*2\r\n-ERR element\r\n:1\r\n", "$-1\r\n")
+	defer listener.Close()
+	store := recaptcha.NewRedisReplayStore(listener.Addr().String())
+	defer store.Close()
+	if _, err := store.Reserve(context.Background(), "a", time.Minute); err == nil || !strings.Contains(err.Error(), "ERR element") {
+		t.Errorf("the error element should be returned but got %v", err)
+	}
+	if ok, err := store.Reserve(context.Background(), "a", time.Minute); ok || err != nil {
+		t.Errorf("the reply of the second command should be read but got %t, %v", ok, err)
+	}
+	if conns := atomic.LoadInt32(conns); conns != 1 {
+		t.Errorf("the connection should have been reused but %d were opened", conns)
+	}
+
+	// a malformed element leaves the connection in an unknown state, it is not reused
+	listener, conns = scriptedRedis(t, "*2\r\n:abc\r\n:1\r\n", "$-1\r\n")
+	defer listener.Close()
+	store = recaptcha.NewRedisReplayStore(listener.Addr().String())
+	defer store.Close()
+	if _, err := store.Reserve(context.Background(), "a", time.Minute); err == nil {
+		t.Error("the malformed reply should be reported")
+	}
+	if ok, err := store.Reserve(context.Background(), "a", time.Minute); ok || err != nil {
+		t.Errorf("the reply of the second command should be read but got %t, %v", ok, err)
+	}
+	if conns := atomic.LoadInt32(conns); conns != 2 {
+		t.Errorf("the connection should have been closed but %d were opened", conns)
+	}
+}
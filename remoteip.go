@@ -0,0 +1,136 @@
+package recaptcha
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseTrustedProxies parses a list of CIDRs (e.g. "10.0.0.0/8") or single IP addresses into networks
+// usable with ClientIP and WithTrustedProxies
+func ParseTrustedProxies(proxies ...string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if !strings.Contains(proxy, "/") {
+			ip := normalizeIP(net.ParseIP(proxy))
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// ClientIP returns the IP address of the user who sent r. The Forwarded (RFC 7239), X-Forwarded-For and
+// X-Real-IP headers (in that order of preference) are only taken into account when the request comes from
+// one of the trusted proxies, and then the closest address not belonging to a trusted proxy is returned.
+// IPv4-mapped IPv6 addresses are returned in their IPv4 form and ports are removed.
+// It returns an empty string if r.RemoteAddr is not a valid address
+func ClientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	ip := parseAddr(r.RemoteAddr)
+	if ip == nil {
+		return ""
+	}
+
+	chain := forwardedFor(r.Header)
+	for i := len(chain) - 1; i >= 0 && isTrusted(ip, trustedProxies); i-- {
+		hop := parseAddr(chain[i])
+		if hop == nil {
+			// obfuscated or unknown hops can't be followed
+			break
+		}
+		ip = hop
+	}
+	return ip.String()
+}
+
+// WithTrustedProxies sets the proxies trusted by the client when it finds out the user's IP of a request,
+// see ClientIP
+func WithTrustedProxies(proxies ...*net.IPNet) Option {
+	return func(c *Client) {
+		c.trustedProxies = append(c.trustedProxies, proxies...)
+	}
+}
+
+// RemoteIP returns the user's IP of a request according to the client trusted proxies, see ClientIP
+func (c *Client) RemoteIP(r *http.Request) string {
+	return ClientIP(r, c.trustedProxies)
+}
+
+// CheckRequest is like Check, using the context and the user's IP of the request
+func (c *Client) CheckRequest(r *http.Request, clientResponse string) (Response, error) {
+	return c.Check(r.Context(), clientResponse, c.RemoteIP(r))
+}
+
+// CheckV3Request is like CheckV3, using the context and the user's IP of the request
+func (c *Client) CheckV3Request(r *http.Request, clientResponse string) (ResponseV3, error) {
+	return c.CheckV3(r.Context(), clientResponse, c.RemoteIP(r))
+}
+
+// forwardedFor returns the addresses of the forwarding chain, from the farthest to the closest
+func forwardedFor(header http.Header) []string {
+	var chain []string
+	if values := header["Forwarded"]; len(values) != 0 {
+		for _, value := range values {
+			for _, element := range strings.Split(value, ",") {
+				for _, pair := range strings.Split(element, ";") {
+					kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+					if len(kv) == 2 && strings.EqualFold(kv[0], "for") {
+						chain = append(chain, strings.Trim(kv[1], `"`))
+					}
+				}
+			}
+		}
+		return chain
+	}
+	if values := header["X-Forwarded-For"]; len(values) != 0 {
+		for _, value := range values {
+			for _, addr := range strings.Split(value, ",") {
+				chain = append(chain, strings.TrimSpace(addr))
+			}
+		}
+		return chain
+	}
+	if value := header.Get("X-Real-IP"); value != "" {
+		chain = append(chain, strings.TrimSpace(value))
+	}
+	return chain
+}
+
+// parseAddr parses an IP address which may carry a port, brackets or a zone
+func parseAddr(addr string) net.IP {
+	addr = strings.TrimSpace(addr)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+	if i := strings.IndexByte(addr, '%'); i >= 0 {
+		addr = addr[:i]
+	}
+	return normalizeIP(net.ParseIP(addr))
+}
+
+func normalizeIP(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
+func isTrusted(ip net.IP, trustedProxies []*net.IPNet) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
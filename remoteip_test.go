@@ -0,0 +1,76 @@
+package recaptcha_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := recaptcha.ParseTrustedProxies("10.0.0.0/8", "192.0.2.10", "2001:db8::/32")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %v", err)
+	}
+	cases := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string][]string
+		expected   string
+	}{
+		{"direct", "203.0.113.5:4321", nil, "203.0.113.5"},
+		{"untrusted proxy headers are ignored", "203.0.113.5:4321", map[string][]string{"X-Forwarded-For": {"198.51.100.1"}}, "203.0.113.5"},
+		{"x-forwarded-for", "10.0.0.1:80", map[string][]string{"X-Forwarded-For": {"198.51.100.1, 10.1.1.1"}}, "198.51.100.1"},
+		{"x-forwarded-for spoofed", "10.0.0.1:80", map[string][]string{"X-Forwarded-For": {"1.1.1.1, 198.51.100.1, 10.1.1.1"}}, "198.51.100.1"},
+		{"x-forwarded-for several headers", "10.0.0.1:80", map[string][]string{"X-Forwarded-For": {"198.51.100.1", "192.0.2.10"}}, "198.51.100.1"},
+		{"all trusted", "10.0.0.1:80", map[string][]string{"X-Forwarded-For": {"10.2.2.2, 10.1.1.1"}}, "10.2.2.2"},
+		{"x-real-ip", "10.0.0.1:80", map[string][]string{"X-Real-Ip": {"198.51.100.1"}}, "198.51.100.1"},
+		{"forwarded", "10.0.0.1:80", map[string][]string{
+			"Forwarded":       {`for=198.51.100.1;proto=https, for="[2001:db8::1]:4711"`},
+			"X-Forwarded-For": {"1.1.1.1"},
+		}, "198.51.100.1"},
+		{"forwarded ipv6", "[2001:db8::5]:443", map[string][]string{"Forwarded": {`For="[2001:db9::1]:4711"`}}, "2001:db9::1"},
+		{"forwarded unknown", "10.0.0.1:80", map[string][]string{"Forwarded": {"for=unknown"}}, "10.0.0.1"},
+		{"ipv4-mapped", "[::ffff:10.0.0.1]:80", map[string][]string{"X-Forwarded-For": {"::ffff:198.51.100.1"}}, "198.51.100.1"},
+		{"port in header", "10.0.0.1:80", map[string][]string{"X-Forwarded-For": {"198.51.100.1:5555"}}, "198.51.100.1"},
+		{"invalid remote address", "pipe", nil, ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.remoteAddr
+		for name, values := range tc.headers {
+			req.Header[name] = values
+		}
+		if ip := recaptcha.ClientIP(req, trusted); ip != tc.expected {
+			t.Errorf("%s: the client IP should be %q but it was %q", tc.name, tc.expected, ip)
+		}
+	}
+}
+
+func TestParseTrustedProxiesInvalid(t *testing.T) {
+	if _, err := recaptcha.ParseTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Error("an error was expected for an invalid CIDR")
+	}
+	if _, err := recaptcha.ParseTrustedProxies("proxy.local"); err == nil {
+		t.Error("an error was expected for an invalid IP")
+	}
+}
+
+func TestClientCheckRequest(t *testing.T) {
+	server := tokenServer(t)
+	defer server.Close()
+	trusted, _ := recaptcha.ParseTrustedProxies("10.0.0.0/8")
+	client := recaptcha.NewClient(
+		recaptcha.WithSecret(apiSecret),
+		recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithTrustedProxies(trusted...),
+	)
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.RemoteAddr = "10.0.0.1:80"
+	req.Header.Set("X-Forwarded-For", "192.0.2.1")
+	resp, err := client.CheckV3Request(req, gResponse)
+	if err != nil || !resp.Success {
+		t.Errorf("the user's IP should be taken from the forwarding headers but got %+v, %v", resp, err)
+	}
+}
@@ -0,0 +1,116 @@
+package recaptcha
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultTokenLifetime is how long a user response token stays valid for the Recaptcha API
+const DefaultTokenLifetime = 2 * time.Minute
+
+// ReplayStore records the user responses seen by a client so repeated ones can be rejected
+// without reaching the API. Keys are hashes of the user responses, never the responses themselves.
+// Implementations must be thread-safe
+type ReplayStore interface {
+	// Reserve records key for ttl, it reports false if the key was already recorded and has not expired yet
+	Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Release forgets key, it is called when a user response fails the verification
+	Release(ctx context.Context, key string) error
+}
+
+// WithReplayStore makes the client reject with ErrTimeoutOrDuplicate any user response that it has already
+// verified successfully (or is verifying right now) during the last ttl, DefaultTokenLifetime is used if ttl is 0
+func WithReplayStore(store ReplayStore, ttl time.Duration) Option {
+	return func(c *Client) {
+		if ttl <= 0 {
+			ttl = DefaultTokenLifetime
+		}
+		c.replayStore = store
+		c.replayTTL = ttl
+	}
+}
+
+// tokenHash returns the key used to identify a user response in caches and stores
+func tokenHash(clientResponse string) string {
+	sum := sha256.Sum256([]byte(clientResponse))
+	return hex.EncodeToString(sum[:])
+}
+
+// guardReplay runs verify once the user response has been reserved in the replay store,
+// the reservation is released unless the response is verified successfully
+func (c *Client) guardReplay(ctx context.Context, clientResponse string, result verdict, verify func() error) error {
+	if c.replayStore == nil {
+		return verify()
+	}
+
+	key := tokenHash(clientResponse)
+	reserved, err := c.replayStore.Reserve(ctx, key, c.replayTTL)
+	if err != nil {
+		return fmt.Errorf("unable to reserve the response in the replay store: %w", err)
+	}
+	if !reserved {
+		result.response().Errors = []error{ErrTimeoutOrDuplicate}
+		return nil
+	}
+
+	err = verify()
+	if err != nil || !result.response().Success {
+		// the request context may be already canceled, the release must happen anyway
+		c.replayStore.Release(context.Background(), key)
+	}
+	return err
+}
+
+// MemoryReplayStore is a ReplayStore which keeps the keys in memory, it is only suitable
+// for applications running a single instance
+type MemoryReplayStore struct {
+	mu        sync.Mutex
+	entries   map[string]time.Time
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+// memorySweepInterval is how often the expired keys are removed from a MemoryReplayStore
+const memorySweepInterval = time.Minute
+
+// NewMemoryReplayStore creates an empty MemoryReplayStore
+func NewMemoryReplayStore() *MemoryReplayStore {
+	return &MemoryReplayStore{
+		entries: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Reserve records key for ttl, it reports false if the key was already recorded and has not expired yet
+func (s *MemoryReplayStore) Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) > memorySweepInterval {
+		for k, expiry := range s.entries {
+			if !now.Before(expiry) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if expiry, ok := s.entries[key]; ok && now.Before(expiry) {
+		return false, nil
+	}
+	s.entries[key] = now.Add(ttl)
+	return true, nil
+}
+
+// Release forgets key
+func (s *MemoryReplayStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,76 @@
+package recaptcha_test
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+func TestMemoryReplayStore(t *testing.T) {
+	store := recaptcha.NewMemoryReplayStore()
+	ctx := context.Background()
+
+	if ok, _ := store.Reserve(ctx, "a", time.Minute); !ok {
+		t.Error("the first reservation should succeed")
+	}
+	if ok, _ := store.Reserve(ctx, "a", time.Minute); ok {
+		t.Error("the second reservation should fail")
+	}
+	store.Release(ctx, "a")
+	if ok, _ := store.Reserve(ctx, "a", time.Minute); !ok {
+		t.Error("the reservation should succeed after a release")
+	}
+	if ok, _ := store.Reserve(ctx, "b", time.Nanosecond); !ok {
+		t.Error("the first reservation should succeed")
+	}
+	time.Sleep(time.Millisecond)
+	if ok, _ := store.Reserve(ctx, "b", time.Minute); !ok {
+		t.Error("the reservation should succeed once the previous one expired")
+	}
+}
+
+func testReplayGuard(t *testing.T, store recaptcha.ReplayStore) {
+	t.Helper()
+	var calls, success int32 = 0, 1
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&success) == 1 {
+			jsonReply(`{"success": true}`)(w, r)
+		} else {
+			jsonReply(`{"success": false, "error-codes": ["invalid-input-response"]}`)(w, r)
+		}
+	})
+	defer server.Close()
+	client := recaptcha.NewClient(
+		recaptcha.WithSecret(apiSecret),
+		recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithReplayStore(store, 0),
+	)
+
+	if resp := client.Verify("token-1", ""); !resp.Success {
+		t.Fatalf("the first verification should succeed but it contains: %+v", resp.Errors)
+	}
+	resp := client.Verify("token-1", "")
+	if resp.Success || len(resp.Errors) != 1 || resp.Errors[0] != recaptcha.ErrTimeoutOrDuplicate {
+		t.Errorf("the errors array should contain ErrTimeoutOrDuplicate but it contains: %+v", resp.Errors)
+	}
+	if calls := atomic.LoadInt32(&calls); calls != 1 {
+		t.Errorf("the API should have been called once but it was called %d times", calls)
+	}
+
+	// failed verifications do not consume the token locally
+	atomic.StoreInt32(&success, 0)
+	client.Verify("token-2", "")
+	client.Verify("token-2", "")
+	if calls := atomic.LoadInt32(&calls); calls != 3 {
+		t.Errorf("the API should have been called 3 times but it was called %d times", calls)
+	}
+}
+
+func TestReplayGuardMemory(t *testing.T) {
+	testReplayGuard(t, recaptcha.NewMemoryReplayStore())
+}
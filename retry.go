@@ -0,0 +1,125 @@
+package recaptcha
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how a client retries the API calls that fail because of transient problems:
+// connection errors, timeouts and 5xx or 429 responses. A verdict returned by the API is never retried
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of requests per call, including the first one
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry (100ms by default)
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts (2s by default)
+	MaxBackoff time.Duration
+	// Multiplier is the factor applied to the delay after every retry (2 by default)
+	Multiplier float64
+	// Jitter randomizes every delay by up to the given fraction of it, e.g. 0.2 means ±20%
+	Jitter float64
+	// MaxElapsed limits the total time spent in a call, retries included. The call context is always honored
+	MaxElapsed time.Duration
+}
+
+// WithRetry makes the client retry transient failures according to the policy
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		if policy.InitialBackoff <= 0 {
+			policy.InitialBackoff = 100 * time.Millisecond
+		}
+		if policy.MaxBackoff <= 0 {
+			policy.MaxBackoff = 2 * time.Second
+		}
+		if policy.Multiplier < 1 {
+			policy.Multiplier = 2
+		}
+		c.retry = &policy
+	}
+}
+
+// exchange performs an API call and returns the response body along with the number of attempts made
+func (c *Client) exchange(ctx context.Context, newRequest func(context.Context) (*http.Request, error)) (body []byte, attempts int, err error) {
+	policy := c.retry
+	if policy == nil {
+		body, err = c.sendHTTPRequest(ctx, newRequest)
+		return body, 1, err
+	}
+	if policy.MaxElapsed > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.MaxElapsed)
+		defer cancel()
+	}
+
+	backoff := policy.InitialBackoff
+	for attempts = 1; ; attempts++ {
+		body, err = c.sendHTTPRequest(ctx, newRequest)
+		if err == nil || attempts >= policy.MaxAttempts || !isTransient(err) || ctx.Err() != nil {
+			return body, attempts, err
+		}
+
+		delay := jitter(backoff, policy.Jitter)
+		var statusErr *HTTPStatusError
+		if errors.As(err, &statusErr) && statusErr.RetryAfter > delay {
+			delay = statusErr.RetryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			// there is no time left for another attempt
+			return body, attempts, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return body, attempts, err
+		}
+
+		backoff = time.Duration(float64(backoff) * policy.Multiplier)
+		if backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// isTransient reports whether a failed API call is worth retrying
+func isTransient(err error) bool {
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return false
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	var transportErr *TransportError
+	return errors.As(err, &transportErr)
+}
+
+func jitter(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + fraction*(2*rand.Float64()-1)))
+}
+
+// parseRetryAfter reads a Retry-After header, which contains either a number of seconds or an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
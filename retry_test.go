@@ -0,0 +1,154 @@
+package recaptcha_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+// flakyServer fails the first failures requests with the given status code
+func flakyServer(t *testing.T, failures int32, status int, body string) (*int32, *httptest.Server) {
+	t.Helper()
+	var calls int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		jsonReply(body)(w, r)
+	})
+	return &calls, server
+}
+
+func retryClient(verifyURL string, policy recaptcha.RetryPolicy) *recaptcha.Client {
+	return recaptcha.NewClient(
+		recaptcha.WithSecret(apiSecret),
+		recaptcha.WithVerifyURL(verifyURL),
+		recaptcha.WithRetry(policy),
+	)
+}
+
+func TestRetryTransientStatus(t *testing.T) {
+	for _, status := range []int{http.StatusServiceUnavailable, http.StatusTooManyRequests} {
+		calls, server := flakyServer(t, 2, status, `{"success": true}`)
+		defer server.Close()
+		client := retryClient(server.URL, recaptcha.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+		resp, err := client.Check(context.Background(), gResponse, "")
+		if err != nil {
+			t.Fatalf("unexpected error occurred: %v", err)
+		}
+		if !resp.Success || resp.Attempts != 3 {
+			t.Errorf("the response should succeed at the third attempt but got %+v", resp)
+		}
+		if atomic.LoadInt32(calls) != 3 {
+			t.Errorf("the API should have been called 3 times but it was called %d times", *calls)
+		}
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	calls, server := flakyServer(t, 5, http.StatusBadGateway, `{"success": true}`)
+	defer server.Close()
+	client := retryClient(server.URL, recaptcha.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, Jitter: 0.5})
+	resp, err := client.Check(context.Background(), gResponse, "")
+	var statusErr *recaptcha.HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("the error should be a 502 HTTPStatusError but it was %v", err)
+	}
+	if resp.Attempts != 2 || atomic.LoadInt32(calls) != 2 {
+		t.Errorf("two attempts were expected but got %d (%d calls)", resp.Attempts, *calls)
+	}
+}
+
+func TestRetryNotOnVerdictOrClientErrors(t *testing.T) {
+	calls, server := flakyServer(t, 0, 0, `{"success": false, "error-codes": ["timeout-or-duplicate"]}`)
+	defer server.Close()
+	client := retryClient(server.URL, recaptcha.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	resp, err := client.Check(context.Background(), gResponse, "")
+	if err != nil || resp.Attempts != 1 || atomic.LoadInt32(calls) != 1 {
+		t.Errorf("verdicts should not be retried, got %d attempts and error %v", resp.Attempts, err)
+	}
+
+	calls, server = flakyServer(t, 5, http.StatusBadRequest, `{"success": true}`)
+	defer server.Close()
+	client = retryClient(server.URL, recaptcha.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	resp, _ = client.Check(context.Background(), gResponse, "")
+	if resp.Attempts != 1 || atomic.LoadInt32(calls) != 1 {
+		t.Errorf("4xx responses should not be retried, got %d attempts", resp.Attempts)
+	}
+}
+
+func TestRetryConnectionErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	var accepted int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&accepted, 1)
+			conn.Close()
+		}
+	}()
+	defer listener.Close()
+
+	client := retryClient("http://"+listener.Addr().String(), recaptcha.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	resp, err := client.Check(context.Background(), gResponse, "")
+	var transportErr *recaptcha.TransportError
+	if !errors.As(err, &transportErr) {
+		t.Fatalf("the error should be a TransportError but it was %v", err)
+	}
+	if resp.Attempts != 3 {
+		t.Errorf("3 attempts were expected but got %d", resp.Attempts)
+	}
+}
+
+func TestRetryHonorsDeadline(t *testing.T) {
+	calls, server := flakyServer(t, 5, http.StatusServiceUnavailable, `{"success": true}`)
+	defer server.Close()
+	client := retryClient(server.URL, recaptcha.RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second, MaxElapsed: 100 * time.Millisecond})
+	start := time.Now()
+	resp, err := client.Check(context.Background(), gResponse, "")
+	if err == nil {
+		t.Fatal("an error was expected")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("the call should give up before sleeping past the deadline but it took %s", elapsed)
+	}
+	if resp.Attempts != 1 || atomic.LoadInt32(calls) != 1 {
+		t.Errorf("a single attempt was expected but got %d", resp.Attempts)
+	}
+}
+
+func TestRetryMaxElapsedWithoutRetries(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		// the request context only ends with the connection once the body is read
+		r.ParseForm()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	})
+	defer server.Close()
+	client := retryClient(server.URL, recaptcha.RetryPolicy{MaxElapsed: 50 * time.Millisecond})
+	start := time.Now()
+	_, err := client.Check(context.Background(), gResponse, "")
+	var transportErr *recaptcha.TransportError
+	if !errors.As(err, &transportErr) {
+		t.Errorf("the error should be a TransportError but it was %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("MaxElapsed should limit a single attempt too but the call took %s", elapsed)
+	}
+}
@@ -0,0 +1,75 @@
+package recaptcha
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// TurnstileVerifyURL is the Cloudflare Turnstile siteverify endpoint
+const TurnstileVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+
+// TurnstileFormField is the form field where the Turnstile widget stores the user response
+const TurnstileFormField = "cf-turnstile-response"
+
+// TurnstileResponse represents the Cloudflare Turnstile verification response
+type TurnstileResponse struct {
+	Response
+	// the action set when the widget was rendered
+	Action string `json:"action"`
+	// the customer data set when the widget was rendered
+	CData string `json:"cdata"`
+	// Metadata holds additional data about the challenge (Enterprise only)
+	Metadata TurnstileMetadata `json:"metadata"`
+}
+
+// TurnstileMetadata holds the additional data of a Turnstile response
+type TurnstileMetadata struct {
+	// an identifier of the visitor device which remains stable across challenges
+	EphemeralID string `json:"ephemeral_id"`
+}
+
+// Turnstile verifies Cloudflare Turnstile user responses, it accepts the same options as NewClient
+// (WithSecret, WithHTTPClient, WithRetry...)
+type Turnstile struct {
+	client *Client
+}
+
+var _ Verifier = (*Turnstile)(nil)
+
+// NewTurnstile creates a Turnstile verifier configured with the given options
+func NewTurnstile(opts ...Option) *Turnstile {
+	return &Turnstile{client: newClient(TurnstileVerifyURL, TurnstileFormField, opts)}
+}
+
+// Check verifies a Turnstile user response, see Client.Check
+func (t *Turnstile) Check(ctx context.Context, clientResponse, remoteIP string) (response Response, err error) {
+	err = t.client.verify(ctx, t.client.secret, clientResponse, remoteIP, &response)
+	return response, err
+}
+
+// CheckTurnstile verifies a Turnstile user response and returns the Turnstile specific fields.
+// Parameters:
+//  - ctx Provides context for cancelation
+//  - clientResponse The user response token provided by the Turnstile widget
+//  - remoteIP (optional) The user's IP address
+//  - idempotencyKey (optional) An UUID which allows to verify the same response several times, Cloudflare
+//    enforces the single use of the response then and the replay store of the client (WithReplayStore) is not used
+func (t *Turnstile) CheckTurnstile(ctx context.Context, clientResponse, remoteIP, idempotencyKey string) (response TurnstileResponse, err error) {
+	var params url.Values
+	if idempotencyKey != "" {
+		params = url.Values{"idempotency_key": {idempotencyKey}}
+	}
+	err = t.client.verifyWithParams(ctx, t.client.secret, clientResponse, remoteIP, params, idempotencyKey != "", &response)
+	return response, err
+}
+
+// FormField returns TurnstileFormField
+func (t *Turnstile) FormField() string {
+	return t.client.formField
+}
+
+// RemoteIP returns the user's IP of a request, see Client.RemoteIP
+func (t *Turnstile) RemoteIP(r *http.Request) string {
+	return t.client.RemoteIP(r)
+}
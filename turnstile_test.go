@@ -0,0 +1,88 @@
+package recaptcha_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+func TestTurnstileCheck(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if key := r.Form.Get("idempotency_key"); key != "2c4bd1c6-9d3e-4d5e-9d0c-9c4f1a4d8f0a" {
+			t.Errorf("unexpected idempotency key %q", key)
+		}
+		jsonReply(`{"success": true, "challenge_ts": "2022-10-06T16:11:21.515Z", "hostname": "example.com",
+			"action": "login", "cdata": "session-1", "metadata": {"ephemeral_id": "x:9f78e0ed210960d7693b167e"}}`)(w, r)
+	})
+	defer server.Close()
+	turnstile := recaptcha.NewTurnstile(recaptcha.WithSecret(apiSecret), recaptcha.WithVerifyURL(server.URL))
+	resp, err := turnstile.CheckTurnstile(context.Background(), gResponse, clientIP, "2c4bd1c6-9d3e-4d5e-9d0c-9c4f1a4d8f0a")
+	if err != nil || !resp.Success {
+		t.Fatalf("the response should be successful but got %+v, %v", resp, err)
+	}
+	if resp.Action != "login" || resp.CData != "session-1" || resp.Hostname != "example.com" || resp.Metadata.EphemeralID != "x:9f78e0ed210960d7693b167e" {
+		t.Errorf("the Turnstile fields were not decoded: %+v", resp)
+	}
+	if turnstile.FormField() != "cf-turnstile-response" {
+		t.Errorf("unexpected form field %s", turnstile.FormField())
+	}
+}
+
+func TestTurnstileErrorCodes(t *testing.T) {
+	cases := map[string]error{
+		"missing-input-secret":   recaptcha.ErrInvalidInputSecret,
+		"invalid-input-secret":   recaptcha.ErrInvalidInputSecret,
+		"missing-input-response": recaptcha.ErrInvalidInputResponse,
+		"invalid-input-response": recaptcha.ErrInvalidInputResponse,
+		"invalid-widget-id":      recaptcha.ErrInvalidWidgetID,
+		"invalid-parsed-secret":  recaptcha.ErrInvalidParsedSecret,
+		"bad-request":            recaptcha.ErrBadRequest,
+		"timeout-or-duplicate":   recaptcha.ErrTimeoutOrDuplicate,
+		"internal-error":         recaptcha.ErrInternalError,
+	}
+	for code, expected := range cases {
+		server := newTestServer(t, jsonReply(`{"success": false, "error-codes": ["`+code+`"]}`))
+		defer server.Close()
+		turnstile := recaptcha.NewTurnstile(recaptcha.WithSecret(apiSecret), recaptcha.WithVerifyURL(server.URL))
+		resp, err := turnstile.Check(context.Background(), gResponse, "")
+		if err != nil {
+			t.Fatalf("unexpected error occurred: %v", err)
+		}
+		if len(resp.Errors) != 1 || resp.Errors[0] != expected {
+			t.Errorf("%s should be mapped to %v but got %+v", code, expected, resp.Errors)
+		}
+	}
+}
+
+func TestTurnstileIdempotencyKeyWithReplayStore(t *testing.T) {
+	var calls int
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		jsonReply(`{"success": true, "hostname": "example.com"}`)(w, r)
+	})
+	defer server.Close()
+	turnstile := recaptcha.NewTurnstile(recaptcha.WithSecret(apiSecret), recaptcha.WithVerifyURL(server.URL),
+		recaptcha.WithReplayStore(recaptcha.NewMemoryReplayStore(), 0))
+
+	for i := 0; i < 2; i++ {
+		resp, err := turnstile.CheckTurnstile(context.Background(), gResponse, "", "2c4bd1c6-9d3e-4d5e-9d0c-9c4f1a4d8f0a")
+		if err != nil || !resp.Success {
+			t.Errorf("verification %d with the idempotency key should succeed but got %+v, %v", i+1, resp, err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("every verification with the idempotency key should reach Cloudflare but it was called %d times", calls)
+	}
+
+	// without the key the replay guard still applies
+	turnstile.Check(context.Background(), "other-token", "")
+	if resp, _ := turnstile.Check(context.Background(), "other-token", ""); resp.Success || len(resp.Errors) != 1 || resp.Errors[0] != recaptcha.ErrTimeoutOrDuplicate {
+		t.Errorf("the replayed response should be rejected but got %+v", resp)
+	}
+	if calls != 3 {
+		t.Errorf("the replayed response should be rejected locally but the API was called %d times", calls)
+	}
+}
@@ -0,0 +1,28 @@
+package recaptcha
+
+import (
+	"context"
+	"net/http"
+)
+
+// Verifier is implemented by every captcha provider of this package, it allows the code using it
+// (like Middleware) to work with any of them
+type Verifier interface {
+	// Check verifies a user response, transport and protocol failures are reported through err
+	// while response.Errors only holds the error codes returned by the provider
+	Check(ctx context.Context, clientResponse, remoteIP string) (Response, error)
+	// FormField returns the form field where the provider widget stores the user response
+	FormField() string
+}
+
+// scoreVerifier is implemented by the providers whose responses carry a Recaptcha v3 compatible score
+type scoreVerifier interface {
+	CheckV3(ctx context.Context, clientResponse, remoteIP string) (ResponseV3, error)
+}
+
+// remoteIPResolver is implemented by the providers which know how to find out the user's IP of a request
+type remoteIPResolver interface {
+	RemoteIP(r *http.Request) string
+}
+
+var _ Verifier = (*Client)(nil)
@@ -0,0 +1,258 @@
+package recaptcha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// WAF integrations of reCAPTCHA Enterprise carry their tokens in these places
+const (
+	// WAFSessionTokenCookie holds the session token, which is valid for every request of the session
+	WAFSessionTokenCookie = "recaptcha-ca-t"
+	// WAFActionTokenHeader holds the action token, which is valid for a single request
+	WAFActionTokenHeader = "X-Recaptcha-Token"
+)
+
+// WAFTokenKind tells where a WAF token comes from
+type WAFTokenKind int
+
+const (
+	// WAFNoToken means the request has no WAF token
+	WAFNoToken WAFTokenKind = iota
+	// WAFActionToken is a single use token found in the WAFActionTokenHeader header
+	WAFActionToken
+	// WAFSessionToken is a token found in the WAFSessionTokenCookie cookie
+	WAFSessionToken
+)
+
+// WAFToken returns the WAF token of a request, the action token is preferred over the session token
+func WAFToken(r *http.Request) (string, WAFTokenKind) {
+	if token := r.Header.Get(WAFActionTokenHeader); token != "" {
+		return token, WAFActionToken
+	}
+	if cookie, err := r.Cookie(WAFSessionTokenCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, WAFSessionToken
+	}
+	return "", WAFNoToken
+}
+
+// FirewallActionType is the kind of a firewall policy action
+type FirewallActionType string
+
+// The actions of the firewall policies
+const (
+	// FirewallAllow lets the request through
+	FirewallAllow FirewallActionType = "allow"
+	// FirewallBlock rejects the request
+	FirewallBlock FirewallActionType = "block"
+	// FirewallRedirect sends the user to a reCAPTCHA challenge page
+	FirewallRedirect FirewallActionType = "redirect"
+	// FirewallSubstitute serves the content at FirewallAction.Path of the same host instead of the requested one
+	FirewallSubstitute FirewallActionType = "substitute"
+	// FirewallSetHeader adds a header to the request forwarded to the backend
+	FirewallSetHeader FirewallActionType = "set_header"
+	// FirewallIncludeRecaptchaScript injects the reCAPTCHA script into the HTML response
+	FirewallIncludeRecaptchaScript FirewallActionType = "include_recaptcha_script"
+)
+
+// FirewallAction is an action of a firewall policy
+type FirewallAction struct {
+	Type FirewallActionType
+	// Path is the path of the substitute content of FirewallSubstitute
+	Path string
+	// HeaderKey and HeaderValue are the header set by FirewallSetHeader
+	HeaderKey   string
+	HeaderValue string
+}
+
+// UnmarshalJSON decodes the API representation of an action, where the type is given by the only field present
+func (a *FirewallAction) UnmarshalJSON(data []byte) error {
+	var action struct {
+		Allow                  *struct{} `json:"allow"`
+		Block                  *struct{} `json:"block"`
+		Redirect               *struct{} `json:"redirect"`
+		IncludeRecaptchaScript *struct{} `json:"includeRecaptchaScript"`
+		Substitute             *struct {
+			Path string `json:"path"`
+		} `json:"substitute"`
+		SetHeader *struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"setHeader"`
+	}
+	if err := json.Unmarshal(data, &action); err != nil {
+		return err
+	}
+
+	*a = FirewallAction{}
+	switch {
+	case action.Allow != nil:
+		a.Type = FirewallAllow
+	case action.Block != nil:
+		a.Type = FirewallBlock
+	case action.Redirect != nil:
+		a.Type = FirewallRedirect
+	case action.IncludeRecaptchaScript != nil:
+		a.Type = FirewallIncludeRecaptchaScript
+	case action.Substitute != nil:
+		a.Type, a.Path = FirewallSubstitute, action.Substitute.Path
+	case action.SetHeader != nil:
+		a.Type, a.HeaderKey, a.HeaderValue = FirewallSetHeader, action.SetHeader.Key, action.SetHeader.Value
+	}
+	return nil
+}
+
+// FirewallPolicy is the firewall policy matched by a request
+type FirewallPolicy struct {
+	// Name is the resource name of the policy (projects/{project}/firewallpolicies/{policy})
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Path        string           `json:"path"`
+	Condition   string           `json:"condition"`
+	Actions     []FirewallAction `json:"actions"`
+}
+
+// FirewallPolicyAssessment is the result of the firewall policy evaluation of a request
+type FirewallPolicyAssessment struct {
+	// Policy is the policy matched by the request, it is nil when none matched
+	Policy *FirewallPolicy `json:"firewallPolicy"`
+	// Error is set when the policies could not be evaluated
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// FirewallDecision holds the actions of the firewall policy matched by a request, see Execute
+type FirewallDecision struct {
+	// Policy is the resource name of the matched policy, it is empty when none matched
+	Policy  string
+	Actions []FirewallAction
+}
+
+// FirewallDecision returns the decision of the firewall policy evaluation of the assessment
+func (r *EnterpriseResponse) FirewallDecision() FirewallDecision {
+	if r.Firewall == nil || r.Firewall.Policy == nil {
+		return FirewallDecision{}
+	}
+	return FirewallDecision{Policy: r.Firewall.Policy.Name, Actions: r.Firewall.Policy.Actions}
+}
+
+// AssessRequest creates an assessment of a request protected by a WAF integration, see WAFToken. The firewall
+// policies are evaluated even when the request has no token. Action tokens go through the replay protection
+// and the idempotency cache of the client while session tokens, which are reused by design, do not
+func (e *Enterprise) AssessRequest(r *http.Request) (response EnterpriseResponse, err error) {
+	token, kind := WAFToken(r)
+	event := Event{
+		Token:                    token,
+		SiteKey:                  e.siteKey,
+		UserIPAddress:            e.RemoteIP(r),
+		UserAgent:                r.UserAgent(),
+		RequestedURI:             requestURI(r),
+		WAFTokenAssessment:       kind != WAFNoToken,
+		FirewallPolicyEvaluation: true,
+	}
+	if kind != WAFActionToken {
+		err = e.createAssessment(r.Context(), event, &response)
+		return response, err
+	}
+	err = e.client.protect(r.Context(), token, event.UserIPAddress, eventFingerprint(event), &response, func() error {
+		return e.createAssessment(r.Context(), event, &response)
+	})
+	return response, err
+}
+
+// requestURI returns the absolute URL of a server request
+func requestURI(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// FirewallOptions configures FirewallMiddleware
+type FirewallOptions struct {
+	// ChallengeURL is where FirewallRedirect sends the users, the requests are blocked when it is empty
+	ChallengeURL string
+	// OnBlock (optional) handles the blocked requests, by default it replies 403 Forbidden
+	OnBlock http.Handler
+	// OnError (optional) handles the requests whose assessment failed, by default it replies 503 Service Unavailable
+	OnError func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// withDefaults fills the unset handlers of the options
+func (opts FirewallOptions) withDefaults() FirewallOptions {
+	if opts.OnBlock == nil {
+		opts.OnBlock = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		})
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		}
+	}
+	return opts
+}
+
+// Execute runs the actions of the decision in order: headers are set on the request, then the request is
+// allowed (served by next), blocked, redirected to the challenge or served by next with the substitute path.
+// The request is allowed when no terminal action is found. FirewallIncludeRecaptchaScript is left to the application
+func (d FirewallDecision) Execute(w http.ResponseWriter, r *http.Request, next http.Handler, opts FirewallOptions) {
+	opts = opts.withDefaults()
+	for _, action := range d.Actions {
+		switch action.Type {
+		case FirewallSetHeader:
+			r.Header.Set(action.HeaderKey, action.HeaderValue)
+		case FirewallAllow:
+			next.ServeHTTP(w, r)
+			return
+		case FirewallBlock:
+			opts.OnBlock.ServeHTTP(w, r)
+			return
+		case FirewallRedirect:
+			if opts.ChallengeURL == "" {
+				opts.OnBlock.ServeHTTP(w, r)
+				return
+			}
+			http.Redirect(w, r, opts.ChallengeURL, http.StatusFound)
+			return
+		case FirewallSubstitute:
+			substitute := r.Clone(r.Context())
+			substitute.URL.Path, substitute.URL.RawPath = action.Path, ""
+			substitute.RequestURI = substitute.URL.RequestURI()
+			next.ServeHTTP(w, substitute)
+			return
+		}
+	}
+	next.ServeHTTP(w, r)
+}
+
+// FirewallMiddleware returns an HTTP middleware which assesses every request with AssessRequest and executes
+// the decision of the firewall policies (see FirewallDecision.Execute). The assessment is stored in the request
+// context, see ResponseFromContext and EnterpriseResponseFromContext
+func FirewallMiddleware(e *Enterprise, opts FirewallOptions) func(http.Handler) http.Handler {
+	opts = opts.withDefaults()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			response, err := e.AssessRequest(r)
+			if err != nil {
+				opts.OnError(w, r, err)
+				return
+			}
+			ctx := context.WithValue(r.Context(), contextKey{}, response.ResponseV3)
+			ctx = context.WithValue(ctx, enterpriseContextKey{}, response)
+			response.FirewallDecision().Execute(w, r.WithContext(ctx), next, opts)
+		})
+	}
+}
+
+type enterpriseContextKey struct{}
+
+// EnterpriseResponseFromContext returns the assessment stored by FirewallMiddleware in a request context
+func EnterpriseResponseFromContext(ctx context.Context) (EnterpriseResponse, bool) {
+	response, ok := ctx.Value(enterpriseContextKey{}).(EnterpriseResponse)
+	return response, ok
+}
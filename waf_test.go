@@ -0,0 +1,120 @@
+package recaptcha_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/claudio4/go-recaptcha"
+)
+
+func TestWAFToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token, kind := recaptcha.WAFToken(req); token != "" || kind != recaptcha.WAFNoToken {
+		t.Errorf("no token should be found but got %q (%d)", token, kind)
+	}
+	req.AddCookie(&http.Cookie{Name: recaptcha.WAFSessionTokenCookie, Value: "session"})
+	if token, kind := recaptcha.WAFToken(req); token != "session" || kind != recaptcha.WAFSessionToken {
+		t.Errorf("the session token should be found but got %q (%d)", token, kind)
+	}
+	req.Header.Set(recaptcha.WAFActionTokenHeader, "action")
+	if token, kind := recaptcha.WAFToken(req); token != "action" || kind != recaptcha.WAFActionToken {
+		t.Errorf("the action token should be preferred but got %q (%d)", token, kind)
+	}
+}
+
+// firewallServer replies with the actions of the policy matching the requested path
+func firewallServer(t *testing.T, actions map[string]string) *httptest.Server {
+	t.Helper()
+	return enterpriseServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+		event, _ := body["event"].(map[string]interface{})
+		if event["firewallPolicyEvaluation"] != true || event["userAgent"] != "test-agent" {
+			t.Errorf("unexpected event %v", event)
+		}
+		if _, hasToken := event["token"]; hasToken != (event["wafTokenAssessment"] == true) {
+			t.Errorf("wafTokenAssessment should only be set along with a token: %v", event)
+		}
+		policy, ok := actions[event["requestedUri"].(string)]
+		if !ok {
+			jsonReply(`{"tokenProperties": {"valid": true}, "firewallPolicyAssessment": {}}`)(w, r)
+			return
+		}
+		jsonReply(`{"tokenProperties": {"valid": true}, "firewallPolicyAssessment": {"firewallPolicy": {
+			"name": "projects/my-project/firewallpolicies/1", "actions": `+policy+`}}}`)(w, r)
+	})
+}
+
+func TestFirewallMiddleware(t *testing.T) {
+	server := firewallServer(t, map[string]string{
+		"http://example.com/allow":      `[{"setHeader": {"key": "X-Bot", "value": "no"}}, {"allow": {}}]`,
+		"http://example.com/block":      `[{"block": {}}]`,
+		"http://example.com/challenge":  `[{"redirect": {}}]`,
+		"http://example.com/substitute": `[{"substitute": {"path": "/honeypot"}}]`,
+	})
+	defer server.Close()
+	enterprise := newTestEnterprise(server.URL)
+	var gotPath, gotHeader string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotHeader = r.URL.Path, r.Header.Get("X-Bot")
+		if _, ok := recaptcha.EnterpriseResponseFromContext(r.Context()); !ok {
+			t.Error("the assessment should be stored in the context")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := recaptcha.FirewallMiddleware(enterprise, recaptcha.FirewallOptions{ChallengeURL: "/recaptcha-challenge"})(next)
+
+	cases := []struct {
+		path, token string
+		status      int
+		servedPath  string
+	}{
+		{"/allow", "action", http.StatusNoContent, "/allow"},
+		{"/block", "", http.StatusForbidden, ""},
+		{"/challenge", "session", http.StatusFound, ""},
+		{"/substitute", "session", http.StatusNoContent, "/honeypot"},
+		{"/other", "", http.StatusNoContent, "/other"},
+	}
+	for _, tc := range cases {
+		gotPath, gotHeader = "", ""
+		req := httptest.NewRequest(http.MethodGet, "http://example.com"+tc.path, nil)
+		req.Header.Set("User-Agent", "test-agent")
+		if tc.token == "action" {
+			req.Header.Set(recaptcha.WAFActionTokenHeader, gResponse)
+		} else if tc.token == "session" {
+			req.AddCookie(&http.Cookie{Name: recaptcha.WAFSessionTokenCookie, Value: gResponse})
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.status || gotPath != tc.servedPath {
+			t.Errorf("%s: expected %d serving %q but got %d serving %q", tc.path, tc.status, tc.servedPath, rec.Code, gotPath)
+		}
+		if tc.status == http.StatusFound && rec.Header().Get("Location") != "/recaptcha-challenge" {
+			t.Errorf("%s: unexpected redirect to %s", tc.path, rec.Header().Get("Location"))
+		}
+		if tc.path == "/allow" && gotHeader != "no" {
+			t.Errorf("the X-Bot header should be set but it was %q", gotHeader)
+		}
+	}
+}
+
+func TestFirewallMiddlewareSessionTokenReuse(t *testing.T) {
+	server := firewallServer(t, map[string]string{})
+	defer server.Close()
+	enterprise := newTestEnterprise(server.URL, recaptcha.WithReplayStore(recaptcha.NewMemoryReplayStore(), 0))
+	handler := recaptcha.FirewallMiddleware(enterprise, recaptcha.FirewallOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, _ := recaptcha.ResponseFromContext(r.Context())
+		if !resp.Success {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		req.Header.Set("User-Agent", "test-agent")
+		req.AddCookie(&http.Cookie{Name: recaptcha.WAFSessionTokenCookie, Value: gResponse})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("the session token should be accepted on every request but request %d got %d", i, rec.Code)
+		}
+	}
+}